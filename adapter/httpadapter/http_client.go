@@ -47,6 +47,21 @@ type TracingRoundTripper struct {
 	// Base 底层的 Transport，实际执行 HTTP 请求
 	// 如果为 nil，使用 http.DefaultTransport
 	Base http.RoundTripper
+
+	// IsErrorStatus 判断响应状态码是否将 span 标记为错误
+	// 如果为 nil，使用 DefaultIsErrorStatus（4xx 和 5xx 都视为错误）
+	// 只希望 5xx 视为错误时，可以使用 ServerErrorStatus
+	IsErrorStatus func(statusCode int) bool
+}
+
+// DefaultIsErrorStatus 客户端默认的错误判定：4xx 和 5xx 都视为错误
+func DefaultIsErrorStatus(statusCode int) bool {
+	return statusCode >= 400
+}
+
+// ServerErrorStatus 只将 5xx 视为错误（4xx 属于业务预期结果时使用，如 404 查询不存在）
+func ServerErrorStatus(statusCode int) bool {
+	return statusCode >= 500
 }
 
 // RoundTrip 实现 http.RoundTripper 接口
@@ -56,7 +71,7 @@ type TracingRoundTripper struct {
 //   2. 创建 Exit Span（表示调用外部服务）
 //   3. 自动注入 trace_id 到请求头
 //   4. 调用 Base.RoundTrip 执行实际的 HTTP 请求
-//   5. 记录 HTTP 状态码到 Span
+//   5. 记录 HTTP 状态码到 Span（按 IsErrorStatus 设置错误状态）
 //   6. 完成 Span
 //
 // **注入的请求头**（W3C Trace Context 标准）：
@@ -82,8 +97,14 @@ func (t *TracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 		return t.base().RoundTrip(req)
 	}
 
-	// 1. 创建 Exit Span（调用外部服务）
-	span, spanCtx := tracer.StartSpan(ctx, "HTTP/"+req.Method)
+	// 1. 创建 Exit Span（调用外部服务），HTTP 基本信息在创建时设置，采样器可以看到
+	span, spanCtx := zltrace.StartSpanWithOptions(tracer, ctx, "HTTP/"+req.Method,
+		zltrace.WithSpanKind(zltrace.SpanKindClient),
+		zltrace.WithAttributes(
+			zltrace.Attr("http.url", req.URL.String()),
+			zltrace.Attr("http.method", req.Method),
+			zltrace.Attr("http.host", req.URL.Host),
+		))
 	defer span.Finish()
 
 	// 2. 自动注入 trace_id 到请求头
@@ -92,12 +113,7 @@ func (t *TracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 		// 注入失败不应该阻止请求，继续执行
 	}
 
-	// 3. 设置 span 标签（HTTP 基本信息）
-	span.SetTag("http.url", req.URL.String())
-	span.SetTag("http.method", req.Method)
-	span.SetTag("http.host", req.URL.Host)
-
-	// 4. 执行实际的 HTTP 请求（使用带有 span 的 context）
+	// 3. 执行实际的 HTTP 请求（使用带有 span 的 context）
 	resp, err := t.base().RoundTrip(req.WithContext(spanCtx))
 
 	if err != nil {
@@ -106,17 +122,27 @@ func (t *TracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 		return nil, err
 	}
 
-	// 5. 记录响应状态码到 span
+	// 4. 记录响应状态码到 span
 	span.SetTag("http.status_code", resp.StatusCode)
 
-	// 如果是 4xx 或 5xx，记录为错误
-	if resp.StatusCode >= 400 {
-		span.SetTag("error", fmt.Sprintf("HTTP %d", resp.StatusCode))
+	// 按 IsErrorStatus 规则标记错误（默认 4xx 和 5xx）
+	if t.isErrorStatus(resp.StatusCode) {
+		description := fmt.Sprintf("HTTP %d", resp.StatusCode)
+		span.SetStatus(zltrace.StatusError, description)
+		span.SetTag("error", description)
 	}
 
 	return resp, nil
 }
 
+// isErrorStatus 判断状态码是否视为错误
+func (t *TracingRoundTripper) isErrorStatus(statusCode int) bool {
+	if t.IsErrorStatus != nil {
+		return t.IsErrorStatus(statusCode)
+	}
+	return DefaultIsErrorStatus(statusCode)
+}
+
 // base 获取底层的 Transport
 func (t *TracingRoundTripper) base() http.RoundTripper {
 	if t.Base != nil {
@@ -0,0 +1,233 @@
+package httpadapter
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zlxdbj/zltrace"
+	"github.com/zlxdbj/zltrace/zltracetest"
+)
+
+func TestTracingRoundTripperInjectsTraceParent(t *testing.T) {
+	zltrace.RegisterTracer(&mockTracer{})
+	defer zltrace.RegisterTracer(nil)
+
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	resp, err := NewTracedClient(nil).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got == "" {
+		t.Error("traceparent header should be injected")
+	}
+}
+
+func TestTracingRoundTripperStatusMapping(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		isErrorStatus func(int) bool
+		want          zltrace.StatusCode
+	}{
+		{"200 default", http.StatusOK, nil, zltrace.StatusUnset},
+		{"404 default", http.StatusNotFound, nil, zltrace.StatusError},
+		{"500 default", http.StatusInternalServerError, nil, zltrace.StatusError},
+		{"404 server errors only", http.StatusNotFound, ServerErrorStatus, zltrace.StatusUnset},
+		{"503 server errors only", http.StatusServiceUnavailable, ServerErrorStatus, zltrace.StatusError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := &mockTracer{}
+			zltrace.RegisterTracer(tracer)
+			defer zltrace.RegisterTracer(nil)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &TracingRoundTripper{IsErrorStatus: tt.isErrorStatus}}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			span := tracer.lastSpan
+			if span.status != tt.want {
+				t.Errorf("status = %v, want %v", span.status, tt.want)
+			}
+			if span.tags["http.status_code"] != tt.statusCode {
+				t.Errorf("http.status_code = %v, want %d", span.tags["http.status_code"], tt.statusCode)
+			}
+			if !span.finished {
+				t.Error("span should be finished")
+			}
+		})
+	}
+}
+
+func TestTracingRoundTripperSpan(t *testing.T) {
+	rec := zltracetest.New(t)
+
+	// 服务端从 header 中提取 trace 上下文并创建 server span
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, _ := rec.Tracer().Extract(r.Context(), zltracetest.MapCarrier{"traceparent": r.Header.Get("traceparent")})
+		span, _ := zltrace.StartSpanWithKind(rec.Tracer(), ctx, "GET /users", zltrace.SpanKindServer)
+		span.Finish()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	parent, ctx := rec.Tracer().StartSpan(context.Background(), "handler")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/users", nil)
+	resp, err := NewTracedClient(nil).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	parent.Finish()
+
+	client := rec.MustSpan(t, "HTTP/GET")
+	zltracetest.AssertParent(t, rec.MustSpan(t, "handler"), client)
+	zltracetest.AssertKind(t, client, zltrace.SpanKindClient)
+	zltracetest.AssertStatus(t, client, zltrace.StatusError)
+	zltracetest.AssertAttribute(t, client, "http.method", "GET")
+	zltracetest.AssertAttribute(t, client, "http.status_code", http.StatusNotFound)
+	zltracetest.AssertRemoteParent(t, client, rec.MustSpan(t, "GET /users"))
+}
+
+// serverHandler 将 *http.Request 适配为 zltrace.HTTPTraceHandler，模拟被调用的服务端
+type serverHandler struct {
+	req        *http.Request
+	ctx        context.Context
+	statusCode int
+}
+
+func (h *serverHandler) GetMethod() string                  { return h.req.Method }
+func (h *serverHandler) GetURL() string                     { return h.req.URL.Path }
+func (h *serverHandler) GetHeader(key string) string        { return h.req.Header.Get(key) }
+func (h *serverHandler) SetSpanContext(ctx context.Context) { h.ctx = ctx }
+func (h *serverHandler) GetSpanContext() context.Context    { return h.ctx }
+func (h *serverHandler) GetStatusCode() int                 { return h.statusCode }
+
+var update = flag.Bool("update", false, "update golden trace files in testdata/")
+
+// 更新 golden 文件：go test -run TestTracingRoundTripperGolden -update
+func TestTracingRoundTripperGolden(t *testing.T) {
+	rec := zltracetest.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := &serverHandler{req: r, statusCode: http.StatusOK}
+		if r.URL.Path != "/users" {
+			handler.statusCode = http.StatusNotFound
+		}
+		zltrace.TraceHTTPRequest(r.Context(), handler, func() {})
+		w.WriteHeader(handler.statusCode)
+	}))
+	defer server.Close()
+
+	parent, ctx := rec.Tracer().StartSpan(context.Background(), "handler")
+	client := NewTracedClient(nil)
+	for _, path := range []string{"/users", "/missing"} {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	parent.Finish()
+
+	// httptest.Server 的端口每次不同
+	rec.AssertTree(t, "tracing_round_tripper", zltracetest.MaskAttributes("http.url", "http.host"), zltracetest.Update(*update))
+}
+
+// mockTracer 用于测试，记录最后创建的 span
+type mockTracer struct {
+	lastSpan *mockSpan
+}
+
+func (m *mockTracer) StartSpan(ctx context.Context, operationName string) (zltrace.Span, context.Context) {
+	m.lastSpan = &mockSpan{tags: map[string]interface{}{}}
+	return m.lastSpan, zltrace.ContextWithSpan(ctx, m.lastSpan)
+}
+
+func (m *mockTracer) Inject(ctx context.Context, carrier zltrace.Carrier) error {
+	carrier.Set("traceparent", "00-test123-test456-01")
+	return nil
+}
+
+func (m *mockTracer) Extract(ctx context.Context, carrier zltrace.Carrier) (context.Context, error) {
+	return ctx, nil
+}
+
+func (m *mockTracer) Close() error {
+	return nil
+}
+
+// mockSpan 用于测试，记录标签和状态
+type mockSpan struct {
+	tags     map[string]interface{}
+	status   zltrace.StatusCode
+	finished bool
+}
+
+func (m *mockSpan) Context() context.Context {
+	return context.Background()
+}
+
+func (m *mockSpan) SetTag(key string, value interface{}) {
+	m.tags[key] = value
+}
+
+func (m *mockSpan) SetTags(attrs ...zltrace.Attribute) {
+	for _, attr := range attrs {
+		m.tags[attr.Key] = attr.Value
+	}
+}
+
+func (m *mockSpan) SetError(err error, opts ...zltrace.ErrorOption) {
+	m.status = zltrace.StatusError
+}
+
+func (m *mockSpan) AddEvent(name string, attrs ...zltrace.Attribute) {}
+
+func (m *mockSpan) SetStatus(code zltrace.StatusCode, description string) {
+	m.status = code
+}
+
+func (m *mockSpan) Finish() {
+	m.finished = true
+}
+
+func (m *mockSpan) TraceID() string {
+	return "test123"
+}
+
+func (m *mockSpan) SpanID() string {
+	return "test456"
+}
+
+func (m *mockSpan) IsRecording() bool {
+	return true
+}
+
+func (m *mockSpan) IsSampled() bool {
+	return true
+}
+
+func (m *mockSpan) SpanContext() zltrace.SpanContext {
+	return zltrace.SpanContext{TraceID: "test123", SpanID: "test456", Sampled: true}
+}
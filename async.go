@@ -0,0 +1,84 @@
+package zltrace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zlxdbj/zllog"
+)
+
+// ============================================================================
+// 异步任务 - 在 goroutine 中继续 trace
+// ============================================================================
+
+// Detach 返回与 ctx 脱离取消关系的 context
+//
+// 保留 ctx 中的 span、baggage 和其他值，但去掉取消信号和 deadline。
+// 适用于从 HTTP 请求中派生后台任务：请求结束后 ctx 会被取消，
+// 直接使用 c.Request.Context() 会让后台任务跟着失败；
+// 使用 context.Background() 又会丢失 trace。
+//
+// 使用示例：
+//
+//	go sendEmail(zltrace.Detach(c.Request.Context()), user)
+func Detach(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return context.WithoutCancel(ctx)
+}
+
+// WithLinkedParent 开启新的 trace，并通过 Link 关联 parent（而不是作为子 span）
+//
+// 适用于生命周期远长于请求的后台任务，避免请求的 trace 被拉得很长。
+// parent 为 nil 时只开启新的 trace。
+func WithLinkedParent(parent Span) StartSpanOption {
+	return func(c *StartSpanConfig) {
+		c.NewRoot = true
+		if parent != nil {
+			c.Links = append(c.Links, parent)
+		}
+	}
+}
+
+// Go 在新的 goroutine 中执行 fn，并为它创建一个 span
+//
+// span 在调用 Go 时同步创建（默认作为 ctx 中 span 的子 span），fn 返回后自动结束；
+// fn 返回的错误会记录到 span 上。fn 收到的 context 已经通过 Detach 去掉了取消信号。
+// fn 发生 panic 时会被恢复：panic 记录为 span 的错误（带调用栈）并输出错误日志，不会导致进程退出。
+//
+// 返回的 channel 在 fn 结束后收到它的错误（panic 时为包装后的 panic 错误）然后关闭，
+// 不需要等待时可以忽略。
+//
+// 使用示例：
+//
+//	zltrace.Go(c.Request.Context(), "order.notify", func(ctx context.Context) error {
+//	    return notifier.Send(ctx, order)
+//	})
+//
+//	// 不作为子 span，而是关联到请求的 span
+//	zltrace.Go(ctx, "report.build", buildReport,
+//	    zltrace.WithLinkedParent(zltrace.SpanFromContext(ctx)))
+func Go(ctx context.Context, operationName string, fn func(ctx context.Context) error, opts ...StartSpanOption) <-chan error {
+	span, spanCtx := StartSpanWithOptions(GetSafeTracer(), Detach(ctx), operationName, opts...)
+
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("goroutine panic: %v", r)
+				span.SetError(err, WithStackTrace())
+				span.Finish()
+				zllog.Error(spanCtx, "trace", "异步任务发生 panic", err,
+					zllog.String("operation", operationName))
+			} else {
+				FinishSpan(span, err)
+			}
+			done <- err
+			close(done)
+		}()
+		err = fn(spanCtx)
+	}()
+	return done
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -35,27 +37,201 @@ type TraceConfig struct {
 type SamplerConfig struct {
 	Type  string  `mapstructure:"type"`
 	Ratio float64 `mapstructure:"ratio"`
+	// TracesPerSecond rate_limiting 时每秒最多采样的 trace 数
+	TracesPerSecond float64 `mapstructure:"traces_per_second"`
+	// Root parent_based 时新 trace（没有父 span）使用的采样器，默认 always_on
+	Root RootSamplerConfig `mapstructure:"root"`
+	// RemoteParentSampled parent_based / rate_limiting / rule_based 时上游已采样的处理方式：always_on（默认）、never、root
+	RemoteParentSampled string `mapstructure:"remote_parent_sampled"`
+	// RemoteParentNotSampled parent_based / rate_limiting / rule_based 时上游未采样的处理方式：never（默认）、always_on、root
+	RemoteParentNotSampled string `mapstructure:"remote_parent_not_sampled"`
+	// Rules rule_based 时按顺序匹配的规则，使用第一条匹配规则的采样率
+	Rules []SamplingRule `mapstructure:"rules"`
+	// Default rule_based 时没有规则匹配使用的采样率（只使用 ratio / traces_per_second），默认全量采样
+	Default SamplingRule `mapstructure:"default"`
+}
+
+// SamplingRule rule_based 采样规则
+//
+// 匹配条件之间是"且"的关系，没有配置的条件不限制；ratio 和 traces_per_second 二选一。
+type SamplingRule struct {
+	// Name 规则名称，被采样的根 span 上记录为 sampling.rule 属性
+	Name string `mapstructure:"name"`
+	// SpanName span 名称通配符（* 匹配任意字符，? 匹配单个字符），例如 "GET /health*"
+	SpanName string `mapstructure:"span_name"`
+	// SpanNameRegex span 名称正则表达式，不能与 SpanName 同时配置
+	SpanNameRegex string `mapstructure:"span_name_regex"`
+	// SpanKind span 类型：internal, server, client, producer, consumer
+	SpanKind string `mapstructure:"span_kind"`
+	// Attributes 创建 span 时的属性，值支持通配符，例如 http.route: /health（需要 handler 实现 HTTPRouteHandler）、kafka.topic: "audit-*"
+	Attributes map[string]string `mapstructure:"attributes"`
+	// Ratio 采样比率（0.0 - 1.0），0 表示不采样
+	Ratio *float64 `mapstructure:"ratio"`
+	// TracesPerSecond 每秒最多采样的 trace 数
+	TracesPerSecond float64 `mapstructure:"traces_per_second"`
+}
+
+// RootSamplerConfig parent_based 的根采样器配置
+type RootSamplerConfig struct {
+	Type            string  `mapstructure:"type"`
+	Ratio           float64 `mapstructure:"ratio"`
+	TracesPerSecond float64 `mapstructure:"traces_per_second"`
 }
 
 // ExporterConfig Exporter 配置
 type ExporterConfig struct {
-	Type       string            `mapstructure:"type"`
-	OTLP       OTLPConfig        `mapstructure:"otlp"`
-	MaxQueueSize int               `mapstructure:"max_queue_size"`
+	Type string `mapstructure:"type"`
+	// Types 同时启用多个 exporter，例如 [otlp, stdout]；配置后忽略 Type
+	// 每个 exporter 使用独立的批处理队列，互不阻塞
+	Types []string   `mapstructure:"types"`
+	OTLP  OTLPConfig `mapstructure:"otlp"`
+	// MaxQueueSize 已弃用，请使用 batch.max_queue_size
+	MaxQueueSize int          `mapstructure:"max_queue_size"`
+	Retry        RetryConfig  `mapstructure:"retry"`
+	Stdout       StdoutConfig `mapstructure:"stdout"`
+	File         FileConfig   `mapstructure:"file"`
+	// Failover OTLP 不可用时自动降级到日志输出
+	Failover FailoverConfig `mapstructure:"failover"`
+	// Spool OTLP 导出失败时缓存到磁盘，恢复后重放
+	Spool SpoolConfig `mapstructure:"spool"`
+}
+
+// enabledTypes 返回启用的 exporter 类型（配置了 types 时使用 types，否则使用 type）
+func (c ExporterConfig) enabledTypes() []string {
+	if len(c.Types) > 0 {
+		return c.Types
+	}
+	return []string{c.Type}
+}
+
+// FileConfig 文件 Exporter 配置（type=file 时生效）
+// 每行写入一个 OTLP/JSON 格式的 span，按大小和时间切割文件
+type FileConfig struct {
+	// Path 文件路径，例如 /var/log/trace/spans.jsonl
+	Path string `mapstructure:"path"`
+	// MaxSize 单个文件的最大大小（MB），超过后切割，默认 100
+	MaxSize int `mapstructure:"max_size"`
+	// MaxBackups 保留的旧文件个数，0 表示不按个数清理
+	MaxBackups int `mapstructure:"max_backups"`
+	// MaxAge 旧文件保留天数，0 表示不按天数清理
+	MaxAge int `mapstructure:"max_age"`
+	// Compress 是否使用 gzip 压缩切割后的旧文件
+	Compress bool `mapstructure:"compress"`
+	// RotateInterval 按时间切割的间隔，例如 24h，0 表示只按大小切割
+	RotateInterval time.Duration `mapstructure:"rotate_interval"`
 }
 
-// OTLPConfig OTLP gRPC 配置
+// StdoutConfig stdout（日志）Exporter 配置（type=stdout 时生效）
+type StdoutConfig struct {
+	// Level 输出的日志级别：debug（默认）、info、warn、error
+	Level string `mapstructure:"level"`
+	// OnlyErrors 只输出状态为 Error 的 span
+	OnlyErrors bool `mapstructure:"only_errors"`
+	// SlowThresholdMs 只输出耗时不小于该值（毫秒）的 span，0 表示不过滤
+	// 与 OnlyErrors 同时配置时，满足任意一个条件即输出
+	SlowThresholdMs int `mapstructure:"slow_threshold_ms"`
+}
+
+// RetryConfig 导出失败时的重试配置（指数退避）
+// 时间使用带单位的字符串，例如 500ms、5s、1m
+type RetryConfig struct {
+	// Enabled 是否重试（关闭后失败的批次直接丢弃）
+	Enabled bool `mapstructure:"enabled"`
+	// InitialInterval 第一次重试前的等待时间
+	InitialInterval time.Duration `mapstructure:"initial_interval"`
+	// MaxInterval 两次重试之间的最大等待时间
+	MaxInterval time.Duration `mapstructure:"max_interval"`
+	// MaxElapsedTime 一个批次重试的总时长上限，超过后放弃
+	MaxElapsedTime time.Duration `mapstructure:"max_elapsed_time"`
+}
+
+// FailoverConfig OTLP 自动降级配置（exporter 包含 otlp 时生效）
+// 连续失败后切换到 stdout（LoggingExporter，使用 stdout 配置），定期探测 OTLP，恢复后切换回来
+type FailoverConfig struct {
+	// Enabled 是否开启自动降级
+	Enabled bool `mapstructure:"enabled"`
+	// FailureThreshold 连续失败多少批后降级
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	// ProbeInterval 降级期间探测 OTLP 的间隔，例如 30s
+	ProbeInterval time.Duration `mapstructure:"probe_interval"`
+}
+
+// SpoolConfig OTLP 磁盘缓冲配置（exporter 包含 otlp 时生效）
+// 导出失败的批次写入本地磁盘队列，OTLP 恢复后按速率重放
+type SpoolConfig struct {
+	// Enabled 是否开启磁盘缓冲（不能与 failover 同时开启）
+	Enabled bool `mapstructure:"enabled"`
+	// Dir 磁盘队列目录
+	Dir string `mapstructure:"dir"`
+	// MaxBytes 磁盘队列的最大字节数，超过后删除最旧的数据
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// MaxAge 数据在磁盘队列中的最长保留时间，例如 24h，0 表示不限制
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// ReplayRate 每秒最多重放的 span 数量
+	ReplayRate int `mapstructure:"replay_rate"`
+}
+
+// OTLPConfig OTLP 配置（gRPC 或 HTTP/protobuf）
 type OTLPConfig struct {
 	Endpoint string        `mapstructure:"endpoint"`
 	Timeout  int           `mapstructure:"timeout"`
 	Insecure bool          `mapstructure:"insecure"`
+	TLS      TLSConfig     `mapstructure:"tls"`
+	// Protocol 传输协议：grpc（默认，端口 4317）或 http/protobuf（端口 4318）
+	Protocol string `mapstructure:"protocol"`
+	// URLPath HTTP 请求路径（仅 http/protobuf，默认 /v1/traces）
+	URLPath string `mapstructure:"url_path"`
+	// Headers 每次导出时附带的请求头（gRPC 中为 metadata），例如鉴权 token
+	// 值支持密钥引用：${env:TOKEN} 读取环境变量，file:/run/secrets/token 读取文件
+	Headers map[string]string `mapstructure:"headers"`
+	// Compression 压缩方式：none（默认）或 gzip
+	Compression string `mapstructure:"compression"`
 }
 
-// BatchConfig 批量处理配置
+// OTLP 传输协议
+const (
+	OTLPProtocolGRPC         = "grpc"
+	OTLPProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// TLSConfig TLS 配置（insecure=false 时生效）
+// 都不配置时使用系统根证书校验服务端
+type TLSConfig struct {
+	// CAFile 校验服务端证书的 CA 证书（PEM）
+	CAFile string `mapstructure:"ca_file"`
+	// CertFile、KeyFile 客户端证书和私钥（PEM），用于双向 TLS，必须同时配置
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// ServerName 校验服务端证书时使用的域名（默认取 endpoint 的主机名）
+	ServerName string `mapstructure:"server_name"`
+	// InsecureSkipVerify 跳过服务端证书校验（仅用于调试）
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+	// MinVersion 最低 TLS 版本：1.0, 1.1, 1.2, 1.3（默认 1.2）
+	MinVersion string `mapstructure:"min_version"`
+}
+
+// isSet 是否配置了任意 TLS 选项
+func (c TLSConfig) isSet() bool {
+	return c.CAFile != "" || c.CertFile != "" || c.KeyFile != "" ||
+		c.ServerName != "" || c.InsecureSkipVerify || c.MinVersion != ""
+}
+
+// BatchConfig 批量处理配置（对应 OTel 的 BatchSpanProcessor）
+// 值为 0 时使用 OTel SDK 的默认值
 type BatchConfig struct {
-	BatchSize    int `mapstructure:"batch_size"`
-	Timeout      int `mapstructure:"timeout"`
+	// BatchSize 单次导出的最大 span 数量（不能大于 MaxQueueSize）
+	BatchSize int `mapstructure:"batch_size"`
+	// Timeout 攒批的最长等待时间（秒），配置了 ScheduleDelayMs 时以后者为准
+	Timeout int `mapstructure:"timeout"`
+	// ScheduleDelayMs 攒批的最长等待时间（毫秒）
+	ScheduleDelayMs int `mapstructure:"schedule_delay_ms"`
+	// ExportTimeout 单次导出的超时时间（秒）
+	ExportTimeout int `mapstructure:"export_timeout"`
+	// MaxQueueSize 队列最多缓冲的 span 数量
+	// 优先于 exporter.max_queue_size（已弃用，只在未配置本项时生效）
 	MaxQueueSize int `mapstructure:"max_queue_size"`
+	// BlockOnQueueFull 队列满时阻塞 span 结束，而不是丢弃（会拖慢业务，谨慎开启）
+	BlockOnQueueFull bool `mapstructure:"block_on_queue_full"`
 }
 
 // ============================================================================
@@ -109,47 +285,57 @@ func (l *ConfigLoader) SetEnv(env string) {
 
 // Load 加载配置
 // 按优先级查找配置文件，如果都找不到则使用默认配置
+//
+// 找到的配置文件无法解析或配置无效时返回错误，不会退回默认配置（避免 exporter 等配置被静默替换）。
 func (l *ConfigLoader) Load() (*TraceConfig, error) {
+	config, err := l.loadFromFiles()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		// 7. 使用默认配置
+		return l.getDefaultConfig(), nil
+	}
+	return config, nil
+}
+
+// loadFromFiles 按优先级从配置文件加载配置，没有找到配置文件时返回 nil, nil
+func (l *ConfigLoader) loadFromFiles() (*TraceConfig, error) {
 	// 1. 尝试从 trace.yaml 加载（独立配置文件，推荐）
-	if config, err := l.loadFromTraceYAML(); config != nil && err == nil {
-		return config, nil
+	if config, err := l.loadFromTraceYAML(); config != nil || err != nil {
+		return config, err
 	}
 
 	// 2. 尝试从 application.yaml 加载
-	if config, err := l.loadFromAppYAML("application.yaml"); config != nil && err == nil {
-		return config, nil
+	if config, err := l.loadFromAppYAML("application.yaml"); config != nil || err != nil {
+		return config, err
 	}
 
 	// 3. 尝试从 application_{ENV}.yaml 加载
 	if l.envName != "" {
 		appEnvFile := fmt.Sprintf("application_%s.yaml", l.envName)
-		if config, err := l.loadFromAppYAML(appEnvFile); config != nil && err == nil {
-			return config, nil
+		if config, err := l.loadFromAppYAML(appEnvFile); config != nil || err != nil {
+			return config, err
 		}
 	}
 
 	// 4. 尝试从 zltrace.yaml 加载（向后兼容）
 	for _, dir := range l.configDirs {
 		configPath := filepath.Join(dir, "zltrace.yaml")
-		if config, err := l.loadFromTraceYAMLFile(configPath); config != nil && err == nil {
-			return config, nil
+		if config, err := l.loadFromTraceYAMLFile(configPath); config != nil || err != nil {
+			return config, err
 		}
 	}
 
 	// 5. 尝试从 $ZLTRACE_CONFIG 环境变量加载
 	if envPath := os.Getenv("ZLTRACE_CONFIG"); envPath != "" {
-		if config, err := l.loadFromTraceYAMLFile(envPath); config != nil && err == nil {
-			return config, nil
+		if config, err := l.loadFromTraceYAMLFile(envPath); config != nil || err != nil {
+			return config, err
 		}
 	}
 
 	// 6. 尝试从 /etc/zltrace/config.yaml 加载
-	if config, err := l.loadFromTraceYAMLFile("/etc/zltrace/config.yaml"); config != nil && err == nil {
-		return config, nil
-	}
-
-	// 7. 使用默认配置
-	return l.getDefaultConfig(), nil
+	return l.loadFromTraceYAMLFile("/etc/zltrace/config.yaml")
 }
 
 // loadFromTraceYAML 从独立的 trace.yaml 加载配置
@@ -182,7 +368,11 @@ func (l *ConfigLoader) loadFromTraceYAMLFile(configPath string) (*TraceConfig, e
 		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
 	}
 
-	return l.parseTraceConfig(v)
+	config, err := l.parseTraceConfig(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	return config, nil
 }
 
 // loadFromAppYAML 从 application.yaml 加载 trace 配置
@@ -200,7 +390,8 @@ func (l *ConfigLoader) loadFromAppYAML(filename string) (*TraceConfig, error) {
 		v.SetConfigFile(configPath)
 
 		if err := v.ReadInConfig(); err != nil {
-			continue // 文件读取失败，尝试下一个
+			// application.yaml 属于业务方，读不了不代表 trace 配置有问题，尝试下一个
+			continue
 		}
 
 		// 检查是否有 trace 配置项
@@ -210,7 +401,7 @@ func (l *ConfigLoader) loadFromAppYAML(filename string) (*TraceConfig, error) {
 
 		config, err := l.parseAppTraceConfig(v)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
 		}
 		if config != nil {
 			return config, nil
@@ -247,9 +438,42 @@ func (l *ConfigLoader) parseTraceConfig(v *viper.Viper) (*TraceConfig, error) {
 	if v.IsSet("sampler.ratio") {
 		config.Sampler.Ratio = v.GetFloat64("sampler.ratio")
 	}
+	if v.IsSet("sampler.traces_per_second") {
+		config.Sampler.TracesPerSecond = v.GetFloat64("sampler.traces_per_second")
+	}
+	if v.IsSet("sampler.root.type") {
+		config.Sampler.Root.Type = v.GetString("sampler.root.type")
+	}
+	if v.IsSet("sampler.root.ratio") {
+		config.Sampler.Root.Ratio = v.GetFloat64("sampler.root.ratio")
+	}
+	if v.IsSet("sampler.root.traces_per_second") {
+		config.Sampler.Root.TracesPerSecond = v.GetFloat64("sampler.root.traces_per_second")
+	}
+	if v.IsSet("sampler.remote_parent_sampled") {
+		config.Sampler.RemoteParentSampled = v.GetString("sampler.remote_parent_sampled")
+	}
+	if v.IsSet("sampler.remote_parent_not_sampled") {
+		config.Sampler.RemoteParentNotSampled = v.GetString("sampler.remote_parent_not_sampled")
+	}
+	if v.IsSet("sampler.rules") {
+		if err := v.UnmarshalKey("sampler.rules", &config.Sampler.Rules); err != nil {
+			return nil, fmt.Errorf("failed to parse trace.sampler.rules: %w", err)
+		}
+	}
+	if v.IsSet("sampler.default.ratio") {
+		ratio := v.GetFloat64("sampler.default.ratio")
+		config.Sampler.Default.Ratio = &ratio
+	}
+	if v.IsSet("sampler.default.traces_per_second") {
+		config.Sampler.Default.TracesPerSecond = v.GetFloat64("sampler.default.traces_per_second")
+	}
 	if v.IsSet("exporter.type") {
 		config.Exporter.Type = v.GetString("exporter.type")
 	}
+	if v.IsSet("exporter.types") {
+		config.Exporter.Types = v.GetStringSlice("exporter.types")
+	}
 	if v.IsSet("exporter.otlp.endpoint") {
 		config.Exporter.OTLP.Endpoint = v.GetString("exporter.otlp.endpoint")
 	}
@@ -259,18 +483,107 @@ func (l *ConfigLoader) parseTraceConfig(v *viper.Viper) (*TraceConfig, error) {
 	if v.IsSet("exporter.otlp.insecure") {
 		config.Exporter.OTLP.Insecure = v.GetBool("exporter.otlp.insecure")
 	}
+	parseTLSConfig(v, "exporter.otlp.tls", &config.Exporter.OTLP.TLS)
+	if v.IsSet("exporter.otlp.protocol") {
+		config.Exporter.OTLP.Protocol = v.GetString("exporter.otlp.protocol")
+	}
+	if v.IsSet("exporter.otlp.url_path") {
+		config.Exporter.OTLP.URLPath = v.GetString("exporter.otlp.url_path")
+	}
+	if v.IsSet("exporter.otlp.headers") {
+		config.Exporter.OTLP.Headers = v.GetStringMapString("exporter.otlp.headers")
+	}
+	if v.IsSet("exporter.otlp.compression") {
+		config.Exporter.OTLP.Compression = v.GetString("exporter.otlp.compression")
+	}
 	if v.IsSet("exporter.max_queue_size") {
 		config.Exporter.MaxQueueSize = v.GetInt("exporter.max_queue_size")
 	}
+	if v.IsSet("exporter.stdout.level") {
+		config.Exporter.Stdout.Level = v.GetString("exporter.stdout.level")
+	}
+	if v.IsSet("exporter.stdout.only_errors") {
+		config.Exporter.Stdout.OnlyErrors = v.GetBool("exporter.stdout.only_errors")
+	}
+	if v.IsSet("exporter.stdout.slow_threshold_ms") {
+		config.Exporter.Stdout.SlowThresholdMs = v.GetInt("exporter.stdout.slow_threshold_ms")
+	}
+	if v.IsSet("exporter.file.path") {
+		config.Exporter.File.Path = v.GetString("exporter.file.path")
+	}
+	if v.IsSet("exporter.file.max_size") {
+		config.Exporter.File.MaxSize = v.GetInt("exporter.file.max_size")
+	}
+	if v.IsSet("exporter.file.max_backups") {
+		config.Exporter.File.MaxBackups = v.GetInt("exporter.file.max_backups")
+	}
+	if v.IsSet("exporter.file.max_age") {
+		config.Exporter.File.MaxAge = v.GetInt("exporter.file.max_age")
+	}
+	if v.IsSet("exporter.file.compress") {
+		config.Exporter.File.Compress = v.GetBool("exporter.file.compress")
+	}
+	if v.IsSet("exporter.file.rotate_interval") {
+		config.Exporter.File.RotateInterval = v.GetDuration("exporter.file.rotate_interval")
+	}
+	if v.IsSet("exporter.retry.enabled") {
+		config.Exporter.Retry.Enabled = v.GetBool("exporter.retry.enabled")
+	}
+	if v.IsSet("exporter.retry.initial_interval") {
+		config.Exporter.Retry.InitialInterval = v.GetDuration("exporter.retry.initial_interval")
+	}
+	if v.IsSet("exporter.retry.max_interval") {
+		config.Exporter.Retry.MaxInterval = v.GetDuration("exporter.retry.max_interval")
+	}
+	if v.IsSet("exporter.retry.max_elapsed_time") {
+		config.Exporter.Retry.MaxElapsedTime = v.GetDuration("exporter.retry.max_elapsed_time")
+	}
+	if v.IsSet("exporter.failover.enabled") {
+		config.Exporter.Failover.Enabled = v.GetBool("exporter.failover.enabled")
+	}
+	if v.IsSet("exporter.failover.failure_threshold") {
+		config.Exporter.Failover.FailureThreshold = v.GetInt("exporter.failover.failure_threshold")
+	}
+	if v.IsSet("exporter.failover.probe_interval") {
+		config.Exporter.Failover.ProbeInterval = v.GetDuration("exporter.failover.probe_interval")
+	}
+	if v.IsSet("exporter.spool.enabled") {
+		config.Exporter.Spool.Enabled = v.GetBool("exporter.spool.enabled")
+	}
+	if v.IsSet("exporter.spool.dir") {
+		config.Exporter.Spool.Dir = v.GetString("exporter.spool.dir")
+	}
+	if v.IsSet("exporter.spool.max_bytes") {
+		config.Exporter.Spool.MaxBytes = v.GetInt64("exporter.spool.max_bytes")
+	}
+	if v.IsSet("exporter.spool.max_age") {
+		config.Exporter.Spool.MaxAge = v.GetDuration("exporter.spool.max_age")
+	}
+	if v.IsSet("exporter.spool.replay_rate") {
+		config.Exporter.Spool.ReplayRate = v.GetInt("exporter.spool.replay_rate")
+	}
 	if v.IsSet("batch.batch_size") {
 		config.Batch.BatchSize = v.GetInt("batch.batch_size")
 	}
 	if v.IsSet("batch.timeout") {
 		config.Batch.Timeout = v.GetInt("batch.timeout")
 	}
+	if v.IsSet("batch.schedule_delay_ms") {
+		config.Batch.ScheduleDelayMs = v.GetInt("batch.schedule_delay_ms")
+	}
+	if v.IsSet("batch.export_timeout") {
+		config.Batch.ExportTimeout = v.GetInt("batch.export_timeout")
+	}
 	if v.IsSet("batch.max_queue_size") {
 		config.Batch.MaxQueueSize = v.GetInt("batch.max_queue_size")
+	} else if v.IsSet("exporter.max_queue_size") {
+		// 兼容旧配置：exporter.max_queue_size 只在未配置 batch.max_queue_size 时生效
+		config.Batch.MaxQueueSize = config.Exporter.MaxQueueSize
+	}
+	if v.IsSet("batch.block_on_queue_full") {
+		config.Batch.BlockOnQueueFull = v.GetBool("batch.block_on_queue_full")
 	}
+	clampDefaultBatchSize(&config.Batch, v.IsSet("batch.batch_size"))
 
 	// 验证配置
 	if err := validateConfig(config); err != nil {
@@ -311,9 +624,42 @@ func (l *ConfigLoader) parseAppTraceConfig(v *viper.Viper) (*TraceConfig, error)
 	if v.IsSet("trace.sampler.ratio") {
 		config.Sampler.Ratio = v.GetFloat64("trace.sampler.ratio")
 	}
+	if v.IsSet("trace.sampler.traces_per_second") {
+		config.Sampler.TracesPerSecond = v.GetFloat64("trace.sampler.traces_per_second")
+	}
+	if v.IsSet("trace.sampler.root.type") {
+		config.Sampler.Root.Type = v.GetString("trace.sampler.root.type")
+	}
+	if v.IsSet("trace.sampler.root.ratio") {
+		config.Sampler.Root.Ratio = v.GetFloat64("trace.sampler.root.ratio")
+	}
+	if v.IsSet("trace.sampler.root.traces_per_second") {
+		config.Sampler.Root.TracesPerSecond = v.GetFloat64("trace.sampler.root.traces_per_second")
+	}
+	if v.IsSet("trace.sampler.remote_parent_sampled") {
+		config.Sampler.RemoteParentSampled = v.GetString("trace.sampler.remote_parent_sampled")
+	}
+	if v.IsSet("trace.sampler.remote_parent_not_sampled") {
+		config.Sampler.RemoteParentNotSampled = v.GetString("trace.sampler.remote_parent_not_sampled")
+	}
+	if v.IsSet("trace.sampler.rules") {
+		if err := v.UnmarshalKey("trace.sampler.rules", &config.Sampler.Rules); err != nil {
+			return nil, fmt.Errorf("failed to parse trace.sampler.rules: %w", err)
+		}
+	}
+	if v.IsSet("trace.sampler.default.ratio") {
+		ratio := v.GetFloat64("trace.sampler.default.ratio")
+		config.Sampler.Default.Ratio = &ratio
+	}
+	if v.IsSet("trace.sampler.default.traces_per_second") {
+		config.Sampler.Default.TracesPerSecond = v.GetFloat64("trace.sampler.default.traces_per_second")
+	}
 	if v.IsSet("trace.exporter.type") {
 		config.Exporter.Type = v.GetString("trace.exporter.type")
 	}
+	if v.IsSet("trace.exporter.types") {
+		config.Exporter.Types = v.GetStringSlice("trace.exporter.types")
+	}
 	if v.IsSet("trace.exporter.otlp.endpoint") {
 		config.Exporter.OTLP.Endpoint = v.GetString("trace.exporter.otlp.endpoint")
 	}
@@ -323,18 +669,107 @@ func (l *ConfigLoader) parseAppTraceConfig(v *viper.Viper) (*TraceConfig, error)
 	if v.IsSet("trace.exporter.otlp.insecure") {
 		config.Exporter.OTLP.Insecure = v.GetBool("trace.exporter.otlp.insecure")
 	}
+	parseTLSConfig(v, "trace.exporter.otlp.tls", &config.Exporter.OTLP.TLS)
+	if v.IsSet("trace.exporter.otlp.protocol") {
+		config.Exporter.OTLP.Protocol = v.GetString("trace.exporter.otlp.protocol")
+	}
+	if v.IsSet("trace.exporter.otlp.url_path") {
+		config.Exporter.OTLP.URLPath = v.GetString("trace.exporter.otlp.url_path")
+	}
+	if v.IsSet("trace.exporter.otlp.headers") {
+		config.Exporter.OTLP.Headers = v.GetStringMapString("trace.exporter.otlp.headers")
+	}
+	if v.IsSet("trace.exporter.otlp.compression") {
+		config.Exporter.OTLP.Compression = v.GetString("trace.exporter.otlp.compression")
+	}
 	if v.IsSet("trace.exporter.max_queue_size") {
 		config.Exporter.MaxQueueSize = v.GetInt("trace.exporter.max_queue_size")
 	}
+	if v.IsSet("trace.exporter.stdout.level") {
+		config.Exporter.Stdout.Level = v.GetString("trace.exporter.stdout.level")
+	}
+	if v.IsSet("trace.exporter.stdout.only_errors") {
+		config.Exporter.Stdout.OnlyErrors = v.GetBool("trace.exporter.stdout.only_errors")
+	}
+	if v.IsSet("trace.exporter.stdout.slow_threshold_ms") {
+		config.Exporter.Stdout.SlowThresholdMs = v.GetInt("trace.exporter.stdout.slow_threshold_ms")
+	}
+	if v.IsSet("trace.exporter.file.path") {
+		config.Exporter.File.Path = v.GetString("trace.exporter.file.path")
+	}
+	if v.IsSet("trace.exporter.file.max_size") {
+		config.Exporter.File.MaxSize = v.GetInt("trace.exporter.file.max_size")
+	}
+	if v.IsSet("trace.exporter.file.max_backups") {
+		config.Exporter.File.MaxBackups = v.GetInt("trace.exporter.file.max_backups")
+	}
+	if v.IsSet("trace.exporter.file.max_age") {
+		config.Exporter.File.MaxAge = v.GetInt("trace.exporter.file.max_age")
+	}
+	if v.IsSet("trace.exporter.file.compress") {
+		config.Exporter.File.Compress = v.GetBool("trace.exporter.file.compress")
+	}
+	if v.IsSet("trace.exporter.file.rotate_interval") {
+		config.Exporter.File.RotateInterval = v.GetDuration("trace.exporter.file.rotate_interval")
+	}
+	if v.IsSet("trace.exporter.retry.enabled") {
+		config.Exporter.Retry.Enabled = v.GetBool("trace.exporter.retry.enabled")
+	}
+	if v.IsSet("trace.exporter.retry.initial_interval") {
+		config.Exporter.Retry.InitialInterval = v.GetDuration("trace.exporter.retry.initial_interval")
+	}
+	if v.IsSet("trace.exporter.retry.max_interval") {
+		config.Exporter.Retry.MaxInterval = v.GetDuration("trace.exporter.retry.max_interval")
+	}
+	if v.IsSet("trace.exporter.retry.max_elapsed_time") {
+		config.Exporter.Retry.MaxElapsedTime = v.GetDuration("trace.exporter.retry.max_elapsed_time")
+	}
+	if v.IsSet("trace.exporter.failover.enabled") {
+		config.Exporter.Failover.Enabled = v.GetBool("trace.exporter.failover.enabled")
+	}
+	if v.IsSet("trace.exporter.failover.failure_threshold") {
+		config.Exporter.Failover.FailureThreshold = v.GetInt("trace.exporter.failover.failure_threshold")
+	}
+	if v.IsSet("trace.exporter.failover.probe_interval") {
+		config.Exporter.Failover.ProbeInterval = v.GetDuration("trace.exporter.failover.probe_interval")
+	}
+	if v.IsSet("trace.exporter.spool.enabled") {
+		config.Exporter.Spool.Enabled = v.GetBool("trace.exporter.spool.enabled")
+	}
+	if v.IsSet("trace.exporter.spool.dir") {
+		config.Exporter.Spool.Dir = v.GetString("trace.exporter.spool.dir")
+	}
+	if v.IsSet("trace.exporter.spool.max_bytes") {
+		config.Exporter.Spool.MaxBytes = v.GetInt64("trace.exporter.spool.max_bytes")
+	}
+	if v.IsSet("trace.exporter.spool.max_age") {
+		config.Exporter.Spool.MaxAge = v.GetDuration("trace.exporter.spool.max_age")
+	}
+	if v.IsSet("trace.exporter.spool.replay_rate") {
+		config.Exporter.Spool.ReplayRate = v.GetInt("trace.exporter.spool.replay_rate")
+	}
 	if v.IsSet("trace.batch.batch_size") {
 		config.Batch.BatchSize = v.GetInt("trace.batch.batch_size")
 	}
 	if v.IsSet("trace.batch.timeout") {
 		config.Batch.Timeout = v.GetInt("trace.batch.timeout")
 	}
+	if v.IsSet("trace.batch.schedule_delay_ms") {
+		config.Batch.ScheduleDelayMs = v.GetInt("trace.batch.schedule_delay_ms")
+	}
+	if v.IsSet("trace.batch.export_timeout") {
+		config.Batch.ExportTimeout = v.GetInt("trace.batch.export_timeout")
+	}
 	if v.IsSet("trace.batch.max_queue_size") {
 		config.Batch.MaxQueueSize = v.GetInt("trace.batch.max_queue_size")
+	} else if v.IsSet("trace.exporter.max_queue_size") {
+		// 兼容旧配置：exporter.max_queue_size 只在未配置 batch.max_queue_size 时生效
+		config.Batch.MaxQueueSize = config.Exporter.MaxQueueSize
+	}
+	if v.IsSet("trace.batch.block_on_queue_full") {
+		config.Batch.BlockOnQueueFull = v.GetBool("trace.batch.block_on_queue_full")
 	}
+	clampDefaultBatchSize(&config.Batch, v.IsSet("trace.batch.batch_size"))
 
 	// 验证配置
 	if err := validateConfig(config); err != nil {
@@ -344,6 +779,28 @@ func (l *ConfigLoader) parseAppTraceConfig(v *viper.Viper) (*TraceConfig, error)
 	return config, nil
 }
 
+// parseTLSConfig 解析 TLS 配置，prefix 为配置项前缀（例如 exporter.otlp.tls）
+func parseTLSConfig(v *viper.Viper, prefix string, tls *TLSConfig) {
+	if v.IsSet(prefix + ".ca_file") {
+		tls.CAFile = v.GetString(prefix + ".ca_file")
+	}
+	if v.IsSet(prefix + ".cert_file") {
+		tls.CertFile = v.GetString(prefix + ".cert_file")
+	}
+	if v.IsSet(prefix + ".key_file") {
+		tls.KeyFile = v.GetString(prefix + ".key_file")
+	}
+	if v.IsSet(prefix + ".server_name") {
+		tls.ServerName = v.GetString(prefix + ".server_name")
+	}
+	if v.IsSet(prefix + ".insecure_skip_verify") {
+		tls.InsecureSkipVerify = v.GetBool(prefix + ".insecure_skip_verify")
+	}
+	if v.IsSet(prefix + ".min_version") {
+		tls.MinVersion = v.GetString(prefix + ".min_version")
+	}
+}
+
 // getDefaultConfig 获取默认配置
 func (l *ConfigLoader) getDefaultConfig() *TraceConfig {
 	serviceName := detectServiceName()
@@ -353,6 +810,7 @@ func (l *ConfigLoader) getDefaultConfig() *TraceConfig {
 		Sampler: SamplerConfig{
 			Type:  "always_on",
 			Ratio: 1.0,
+			Root:  RootSamplerConfig{Type: "always_on", Ratio: 1.0},
 		},
 		Exporter: ExporterConfig{
 			Type:        "stdout", // 默认输出到日志（降级模式）
@@ -361,16 +819,50 @@ func (l *ConfigLoader) getDefaultConfig() *TraceConfig {
 				Endpoint: "localhost:4317",
 				Timeout:  10,
 				Insecure: true,
+				Protocol: OTLPProtocolGRPC,
 			},
+			Retry:    defaultRetryConfig(),
+			File:     FileConfig{MaxSize: 100},
+			Failover: defaultFailoverConfig(),
+			Spool:    defaultSpoolConfig(),
 		},
 		Batch: BatchConfig{
-			BatchSize:    512,
-			Timeout:      5,
-			MaxQueueSize: 2048,
+			BatchSize:     512,
+			Timeout:       5,
+			ExportTimeout: 30,
+			MaxQueueSize:  2048,
 		},
 	}
 }
 
+// defaultRetryConfig 默认重试配置（与 OTel SDK 的默认值一致）
+func defaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		Enabled:         true,
+		InitialInterval: 5 * time.Second,
+		MaxInterval:     30 * time.Second,
+		MaxElapsedTime:  time.Minute,
+	}
+}
+
+// defaultFailoverConfig 默认降级配置（默认关闭）
+func defaultFailoverConfig() FailoverConfig {
+	return FailoverConfig{
+		FailureThreshold: defaultFailureThreshold,
+		ProbeInterval:    defaultProbeInterval,
+	}
+}
+
+// defaultSpoolConfig 默认磁盘缓冲配置（默认关闭）
+func defaultSpoolConfig() SpoolConfig {
+	return SpoolConfig{
+		Dir:        "./data/trace-spool",
+		MaxBytes:   100 * 1024 * 1024,
+		MaxAge:     24 * time.Hour,
+		ReplayRate: 1000,
+	}
+}
+
 // ============================================================================
 // 配置加载（旧实现，保留用于向后兼容）
 // ============================================================================
@@ -390,6 +882,7 @@ func LoadConfigLegacy() (*TraceConfig, error) {
 		Sampler: SamplerConfig{
 			Type:  "always_on",
 			Ratio: 1.0,
+			Root:  RootSamplerConfig{Type: "always_on", Ratio: 1.0},
 		},
 		Exporter: ExporterConfig{
 			Type: "stdout", // 默认输出到日志（降级模式）
@@ -399,11 +892,16 @@ func LoadConfigLegacy() (*TraceConfig, error) {
 				Insecure: true,
 			},
 			MaxQueueSize: 2048,
+			Retry:        defaultRetryConfig(),
+			File:         FileConfig{MaxSize: 100},
+			Failover:     defaultFailoverConfig(),
+			Spool:        defaultSpoolConfig(),
 		},
 		Batch: BatchConfig{
-			BatchSize:    512,
-			Timeout:      5,
-			MaxQueueSize: 2048,
+			BatchSize:     512,
+			Timeout:       5,
+			ExportTimeout: 30,
+			MaxQueueSize:  2048,
 		},
 	}
 
@@ -467,6 +965,8 @@ func LoadConfigLegacy() (*TraceConfig, error) {
 		config.Exporter.OTLP.Timeout = 10
 	}
 
+	clampDefaultBatchSize(&config.Batch, viper.IsSet("trace.batch.batch_size"))
+
 	// 4. 验证配置
 	if err := validateConfig(config); err != nil {
 		return nil, fmt.Errorf("invalid trace config: %w", err)
@@ -475,6 +975,16 @@ func LoadConfigLegacy() (*TraceConfig, error) {
 	return config, nil
 }
 
+// clampDefaultBatchSize 没有显式配置 batch_size 时，默认的 batch_size 不超过 max_queue_size
+//
+// 只配置了较小的 max_queue_size（例如 256）时不应因为默认的 512 而校验失败；
+// 显式配置的 batch_size 大于 max_queue_size 仍然是配置错误。
+func clampDefaultBatchSize(batch *BatchConfig, batchSizeSet bool) {
+	if !batchSizeSet && batch.MaxQueueSize > 0 && batch.BatchSize > batch.MaxQueueSize {
+		batch.BatchSize = batch.MaxQueueSize
+	}
+}
+
 // detectServiceName 自动检测服务名称
 // 优先级: 环境变量 > 可执行文件名 > 当前目录名 > 默认值
 func detectServiceName() string {
@@ -533,22 +1043,365 @@ func validateConfig(config *TraceConfig) error {
 		return nil
 	}
 
+	if err := validateSamplerConfig(config.Sampler); err != nil {
+		return err
+	}
+
 	// 验证 exporter 类型
-	switch config.Exporter.Type {
-	case "otlp", "stdout", "none":
+	exporterTypes := config.Exporter.enabledTypes()
+	seen := make(map[string]bool, len(exporterTypes))
+	for _, exporterType := range exporterTypes {
+		switch exporterType {
+		case "otlp", "stdout", "file", "none":
+			// 有效值
+		default:
+			return fmt.Errorf("invalid exporter type: %s (must be otlp, stdout, file, or none)", exporterType)
+		}
+		if seen[exporterType] {
+			return fmt.Errorf("duplicate exporter type in trace.exporter.types: %s", exporterType)
+		}
+		seen[exporterType] = true
+	}
+	if seen["none"] && len(exporterTypes) > 1 {
+		return fmt.Errorf("exporter type none cannot be combined with other exporter types")
+	}
+
+	if err := validateBatchConfig(config.Batch); err != nil {
+		return err
+	}
+
+	for _, exporterType := range exporterTypes {
+		if err := validateExporterConfig(config.Exporter, exporterType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSamplerConfig 验证采样器配置
+func validateSamplerConfig(sampler SamplerConfig) error {
+	switch sampler.Type {
+	case "", "always_on", "never":
+		// 有效值（空值表示 always_on）
+		return nil
+	case "traceid_ratio":
+		return validateSamplerRatio("trace.sampler.ratio", sampler.Ratio)
+	case "rate_limiting":
+		if err := validateTracesPerSecond("trace.sampler.traces_per_second", sampler.TracesPerSecond); err != nil {
+			return err
+		}
+	case "parent_based":
+		switch sampler.Root.Type {
+		case "", "always_on", "never":
+			// 有效值（空值表示 always_on）
+		case "traceid_ratio":
+			if err := validateSamplerRatio("trace.sampler.root.ratio", sampler.Root.Ratio); err != nil {
+				return err
+			}
+		case "rate_limiting":
+			if err := validateTracesPerSecond("trace.sampler.root.traces_per_second", sampler.Root.TracesPerSecond); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid root sampler type: %s (must be always_on, never, traceid_ratio, or rate_limiting)", sampler.Root.Type)
+		}
+	case "rule_based":
+		// 编译规则时会检查匹配条件和采样率
+		if _, err := newRuleBasedSampler(sampler.Rules, sampler.Default); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid sampler type: %s (must be always_on, never, traceid_ratio, parent_based, rate_limiting, or rule_based)", sampler.Type)
+	}
+
+	// parent_based、rate_limiting 和 rule_based 都基于父 span 决定，可以调整上游采样决定的处理方式
+	values := []struct {
+		key   string
+		value string
+	}{
+		{"remote_parent_sampled", sampler.RemoteParentSampled},
+		{"remote_parent_not_sampled", sampler.RemoteParentNotSampled},
+	}
+	for _, v := range values {
+		switch v.value {
+		case "", "always_on", "never", "root":
+			// 有效值（空值表示跟随上游的采样决定）
+		default:
+			return fmt.Errorf("invalid trace.sampler.%s: %s (must be always_on, never, or root)", v.key, v.value)
+		}
+	}
+	return nil
+}
+
+// validateTracesPerSecond 验证限流采样的速率
+func validateTracesPerSecond(key string, tracesPerSecond float64) error {
+	if tracesPerSecond <= 0 {
+		return fmt.Errorf("%s must be greater than 0 when sampler type is rate_limiting: %v", key, tracesPerSecond)
+	}
+	return nil
+}
+
+// validateSamplerRatio 验证采样比率在 0.0 - 1.0 之间
+func validateSamplerRatio(key string, ratio float64) error {
+	if ratio < 0 || ratio > 1 {
+		return fmt.Errorf("%s must be between 0.0 and 1.0: %v", key, ratio)
+	}
+	return nil
+}
+
+// validateExporterConfig 验证某个 exporter 类型的配置
+func validateExporterConfig(exporter ExporterConfig, exporterType string) error {
+	switch exporterType {
+	case "stdout":
+		return validateStdoutConfig(exporter.Stdout)
+	case "file":
+		return validateFileConfig(exporter.File)
+	case "otlp":
+		// 如果是 otlp，验证必要配置
+		if exporter.OTLP.Endpoint == "" {
+			return fmt.Errorf("trace.exporter.otlp.endpoint is required when exporter type is otlp")
+		}
+		if err := validateOTLPConfig(exporter.OTLP); err != nil {
+			return err
+		}
+		if err := validateRetryConfig(exporter.Retry); err != nil {
+			return err
+		}
+		if err := validateFailoverConfig(exporter); err != nil {
+			return err
+		}
+		if err := validateSpoolConfig(exporter); err != nil {
+			return err
+		}
+		return validateTLSConfig(exporter.OTLP)
+	}
+	return nil
+}
+
+// validateOTLPConfig 验证 OTLP 的协议、路径和压缩配置
+func validateOTLPConfig(otlp OTLPConfig) error {
+	switch otlp.Protocol {
+	case "", OTLPProtocolGRPC, OTLPProtocolHTTPProtobuf:
+		// 有效值（空值表示 grpc）
+	default:
+		return fmt.Errorf("invalid otlp protocol: %s (must be grpc or http/protobuf)", otlp.Protocol)
+	}
+
+	if otlp.URLPath != "" {
+		if otlp.Protocol != OTLPProtocolHTTPProtobuf {
+			return fmt.Errorf("trace.exporter.otlp.url_path is only supported when protocol is http/protobuf")
+		}
+		if !strings.HasPrefix(otlp.URLPath, "/") {
+			return fmt.Errorf("trace.exporter.otlp.url_path must start with /: %s", otlp.URLPath)
+		}
+	}
+
+	switch otlp.Compression {
+	case "", "none", "gzip":
+		// 有效值
+	default:
+		return fmt.Errorf("invalid otlp compression: %s (must be none or gzip)", otlp.Compression)
+	}
+
+	// 密钥引用在启动时就要能解析，避免运行中才发现导出被拒绝
+	if _, err := resolveHeaders(otlp.Headers); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateBatchConfig 验证批量处理配置
+func validateBatchConfig(batch BatchConfig) error {
+	values := []struct {
+		key   string
+		value int
+	}{
+		{"batch_size", batch.BatchSize},
+		{"timeout", batch.Timeout},
+		{"schedule_delay_ms", batch.ScheduleDelayMs},
+		{"export_timeout", batch.ExportTimeout},
+		{"max_queue_size", batch.MaxQueueSize},
+	}
+	for _, v := range values {
+		if v.value < 0 {
+			return fmt.Errorf("trace.batch.%s must not be negative: %d", v.key, v.value)
+		}
+	}
+
+	if batch.BatchSize > 0 && batch.MaxQueueSize > 0 && batch.BatchSize > batch.MaxQueueSize {
+		return fmt.Errorf("trace.batch.batch_size (%d) must not be greater than max_queue_size (%d)",
+			batch.BatchSize, batch.MaxQueueSize)
+	}
+
+	return nil
+}
+
+// validateFileConfig 验证文件 Exporter 配置
+func validateFileConfig(file FileConfig) error {
+	if file.Path == "" {
+		return fmt.Errorf("trace.exporter.file.path is required when exporter type is file")
+	}
+	if file.MaxSize < 0 || file.MaxBackups < 0 || file.MaxAge < 0 {
+		return fmt.Errorf("trace.exporter.file max_size, max_backups and max_age must not be negative")
+	}
+	if file.RotateInterval != 0 && file.RotateInterval < time.Second {
+		return fmt.Errorf("trace.exporter.file.rotate_interval must be 0 or at least 1s and include a unit (e.g. 24h): %s", file.RotateInterval)
+	}
+	return nil
+}
+
+// validateStdoutConfig 验证 stdout Exporter 配置
+func validateStdoutConfig(stdout StdoutConfig) error {
+	switch stdout.Level {
+	case "", "debug", "info", "warn", "error":
 		// 有效值
 	default:
-		return fmt.Errorf("invalid exporter type: %s (must be otlp, stdout, or none)", config.Exporter.Type)
+		return fmt.Errorf("invalid stdout exporter level: %s (must be debug, info, warn, or error)", stdout.Level)
+	}
+	if stdout.SlowThresholdMs < 0 {
+		return fmt.Errorf("trace.exporter.stdout.slow_threshold_ms must not be negative: %d", stdout.SlowThresholdMs)
 	}
+	return nil
+}
 
-	// 如果是 otlp，验证必要配置
-	if config.Exporter.Type == "otlp" && config.Exporter.OTLP.Endpoint == "" {
-		return fmt.Errorf("trace.exporter.otlp.endpoint is required when exporter type is otlp")
+// validateRetryConfig 验证重试配置
+func validateRetryConfig(retry RetryConfig) error {
+	if !retry.Enabled {
+		return nil
+	}
+
+	intervals := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"initial_interval", retry.InitialInterval},
+		{"max_interval", retry.MaxInterval},
+		{"max_elapsed_time", retry.MaxElapsedTime},
+	}
+	for _, i := range intervals {
+		// 不带单位的数字会被解析为纳秒，这里当作配置错误处理
+		if i.value < time.Millisecond {
+			return fmt.Errorf("trace.exporter.retry.%s must be at least 1ms and include a unit (e.g. 5s): %s", i.key, i.value)
+		}
+	}
+	if retry.MaxInterval < retry.InitialInterval {
+		return fmt.Errorf("trace.exporter.retry.max_interval must not be less than initial_interval")
 	}
 
 	return nil
 }
 
+// validateFailoverConfig 验证 OTLP 自动降级配置（降级目标使用 stdout 配置）
+func validateFailoverConfig(exporter ExporterConfig) error {
+	failover := exporter.Failover
+	if !failover.Enabled {
+		return nil
+	}
+	if failover.FailureThreshold < 1 {
+		return fmt.Errorf("trace.exporter.failover.failure_threshold must be at least 1: %d", failover.FailureThreshold)
+	}
+	if failover.ProbeInterval < time.Millisecond {
+		return fmt.Errorf("trace.exporter.failover.probe_interval must be at least 1ms and include a unit (e.g. 30s): %s", failover.ProbeInterval)
+	}
+	return validateStdoutConfig(exporter.Stdout)
+}
+
+// validateSpoolConfig 验证 OTLP 磁盘缓冲配置
+func validateSpoolConfig(exporter ExporterConfig) error {
+	spool := exporter.Spool
+	if !spool.Enabled {
+		return nil
+	}
+	if exporter.Failover.Enabled {
+		return fmt.Errorf("trace.exporter.spool and trace.exporter.failover cannot be enabled together")
+	}
+	if spool.Dir == "" {
+		return fmt.Errorf("trace.exporter.spool.dir is required when spool is enabled")
+	}
+	if spool.MaxBytes <= 0 {
+		return fmt.Errorf("trace.exporter.spool.max_bytes must be positive: %d", spool.MaxBytes)
+	}
+	if spool.MaxAge != 0 && spool.MaxAge < time.Millisecond {
+		return fmt.Errorf("trace.exporter.spool.max_age must be 0 or at least 1ms and include a unit (e.g. 24h): %s", spool.MaxAge)
+	}
+	if spool.ReplayRate <= 0 {
+		return fmt.Errorf("trace.exporter.spool.replay_rate must be positive: %d", spool.ReplayRate)
+	}
+	return nil
+}
+
+// validateTLSConfig 验证 OTLP 的 TLS 配置（证书文件必须能够解析，启动时而不是第一次导出时发现错误）
+func validateTLSConfig(otlp OTLPConfig) error {
+	if !otlp.TLS.isSet() {
+		return nil
+	}
+	if otlp.Insecure {
+		return fmt.Errorf("trace.exporter.otlp.tls requires trace.exporter.otlp.insecure to be false")
+	}
+	if (otlp.TLS.CertFile == "") != (otlp.TLS.KeyFile == "") {
+		return fmt.Errorf("trace.exporter.otlp.tls.cert_file and key_file must be set together")
+	}
+	// 与创建 Exporter 时使用同一份解析逻辑
+	if _, err := newTLSConfig(otlp.TLS); err != nil {
+		return fmt.Errorf("invalid trace.exporter.otlp.tls: %w", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// 密钥引用（避免 token 明文写在 yaml 中）
+// ============================================================================
+
+// envRefPattern 匹配 ${env:NAME}
+var envRefPattern = regexp.MustCompile(`\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// resolveHeaders 解析请求头中的密钥引用，返回新的 map
+func resolveHeaders(headers map[string]string) (map[string]string, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	resolved := make(map[string]string, len(headers))
+	for key, value := range headers {
+		v, err := resolveSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("trace.exporter.otlp.headers.%s: %w", key, err)
+		}
+		resolved[key] = v
+	}
+	return resolved, nil
+}
+
+// resolveSecret 解析配置值中的密钥引用
+// 支持两种形式：
+//   - file:/run/secrets/token  读取整个文件内容（去掉首尾空白）
+//   - ${env:TOKEN}             替换为环境变量的值，可以出现在字符串中间，例如 "Bearer ${env:TOKEN}"
+// 引用的环境变量未设置或文件无法读取时返回错误
+func resolveSecret(value string) (string, error) {
+	if path, ok := strings.CutPrefix(value, "file:"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	var missing []string
+	resolved := envRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envRefPattern.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable not set: %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
 // ============================================================================
 // 配置示例（用于文档）
 // ============================================================================
@@ -568,18 +1421,69 @@ service_name: my_service
 
 # 采样配置
 sampler:
-  # 采样类型: always_on, never, traceid_ratio, parent_based
+  # 采样类型: always_on, never, traceid_ratio, parent_based, rate_limiting, rule_based
   type: always_on
   # 采样比率（0.0 - 1.0），仅当 type=traceid_ratio 时生效
   ratio: 1.0
+  # parent_based：有上游 trace 时跟随上游的采样决定，新 trace 使用 root 采样器
+  # 生产环境常用配置：尊重上游决定，新 trace 采样 10%
+  # root:
+  #   type: traceid_ratio  # always_on, never, traceid_ratio, rate_limiting
+  #   ratio: 0.1
+  # 上游已采样 / 未采样时的处理方式: always_on, never, root（使用 root 采样器重新决定）
+  # remote_parent_sampled: always_on
+  # remote_parent_not_sampled: never
+  # rate_limiting：每秒最多采样 traces_per_second 个新 trace（令牌桶），有上游 trace 时与 parent_based 相同
+  # 根 span 上记录实际采样概率 sampling.probability，追踪后端可以据此估算真实请求量
+  # traces_per_second: 100
+  # rule_based：按顺序匹配规则（span 名称、类型、创建时的属性），使用第一条匹配规则的采样率
+  # 有上游 trace 时与 parent_based 相同；修改后调用 zltrace.ReloadSampler() 可以不重启生效
+  # rules:
+  #   - name: health
+  #     span_name: "GET /health*"     # 通配符；正则表达式使用 span_name_regex
+  #     span_kind: server
+  #     ratio: 0                      # 不采样
+  #   - name: audit
+  #     attributes:
+  #       kafka.topic: "audit-*"
+  #     traces_per_second: 1          # 每秒最多 1 个 trace
+  # default:
+  #   ratio: 0.1                      # 其他请求采样 10%
 
 # Exporter 配置（决定追踪数据发送到哪里）
 exporter:
-  # 导出类型: otlp, stdout, none
+  # 导出类型: otlp, stdout, file, none
   # - otlp: 发送到追踪系统（SkyWalking、Jaeger 等）
   # - stdout: 输出到日志（降级模式，不发送到追踪系统）
+  # - file: 写入本地文件（每行一个 OTLP/JSON 格式的 span）
   # - none: 不发送追踪数据（只生成 trace_id）
   type: stdout
+  # 同时启用多个 exporter（配置后忽略 type），每个 exporter 使用独立的批处理队列
+  # types: [otlp, stdout]
+
+  # stdout 配置（type=stdout 时生效）
+  stdout:
+    # 日志级别: debug, info, warn, error
+    level: debug
+    # 只输出状态为 Error 的 span
+    only_errors: false
+    # 只输出耗时不小于该值（毫秒）的 span，0 表示不过滤
+    slow_threshold_ms: 0
+
+  # 文件配置（type=file 时生效）
+  file:
+    # 文件路径
+    path: ./logs/trace/spans.jsonl
+    # 单个文件的最大大小（MB），超过后切割
+    max_size: 100
+    # 保留的旧文件个数，0 表示不按个数清理
+    max_backups: 10
+    # 旧文件保留天数，0 表示不按天数清理
+    max_age: 7
+    # 是否压缩旧文件
+    compress: false
+    # 按时间切割的间隔（如 24h），0 表示只按大小切割
+    rotate_interval: 0
 
   # OTLP gRPC 配置（type=otlp 时生效）
   otlp:
@@ -589,18 +1493,76 @@ exporter:
     timeout: 10
     # 是否使用 insecure 连接（开发环境）
     insecure: true
-
-  # 最大队列大小
+    # TLS 配置（insecure=false 时生效，不配置则使用系统根证书）
+    # tls:
+    #   ca_file: /etc/zltrace/ca.pem         # 校验服务端的 CA 证书
+    #   cert_file: /etc/zltrace/client.pem   # 客户端证书（双向 TLS）
+    #   key_file: /etc/zltrace/client.key    # 客户端私钥（双向 TLS）
+    #   server_name: collector.example.com   # 校验证书使用的域名
+    #   insecure_skip_verify: false          # 跳过证书校验（仅调试）
+    #   min_version: "1.2"                   # 最低 TLS 版本
+    # 传输协议: grpc（默认，端口 4317）, http/protobuf（端口 4318）
+    protocol: grpc
+    # HTTP 请求路径（仅 http/protobuf）
+    # url_path: /v1/traces
+    # 每次导出附带的请求头（例如鉴权 token）
+    # 值支持 ${env:NAME}（环境变量）和 file:/path（文件）引用，避免 token 明文写在配置中
+    # headers:
+    #   authorization: Bearer ${env:OTLP_TOKEN}
+    #   x-tenant-id: file:/run/secrets/tenant
+    # 压缩方式: none, gzip
+    compression: none
+
+  # 最大队列大小（已弃用，请使用 batch.max_queue_size）
   max_queue_size: 2048
 
+  # 导出失败时的重试配置（指数退避，时间需要带单位）
+  retry:
+    # 是否重试（关闭后失败的批次直接丢弃）
+    enabled: true
+    # 第一次重试前的等待时间
+    initial_interval: 5s
+    # 两次重试之间的最大等待时间
+    max_interval: 30s
+    # 一个批次重试的总时长上限
+    max_elapsed_time: 1m
+
+  # OTLP 不可用时自动降级到日志输出（使用上面的 stdout 配置）
+  failover:
+    # 是否开启自动降级
+    enabled: false
+    # 连续失败多少批后降级
+    failure_threshold: 3
+    # 降级期间探测 OTLP 的间隔
+    probe_interval: 30s
+
+  # OTLP 导出失败时缓存到磁盘，恢复后重放（不能与 failover 同时开启）
+  spool:
+    # 是否开启磁盘缓冲
+    enabled: false
+    # 磁盘队列目录
+    dir: ./data/trace-spool
+    # 磁盘队列的最大字节数（默认 100MB），超过后删除最旧的数据
+    max_bytes: 104857600
+    # 数据最长保留时间
+    max_age: 24h
+    # 每秒最多重放的 span 数量
+    replay_rate: 1000
+
 # 批量处理配置
 batch:
-  # 批量发送的最大 span 数量
+  # 批量发送的最大 span 数量（不能大于 max_queue_size）
   batch_size: 512
   # 批量发送的超时时间（秒）
   timeout: 5
-  # 最大队列大小
+  # 批量发送的超时时间（毫秒，配置后优先于 timeout）
+  # schedule_delay_ms: 5000
+  # 单次导出的超时时间（秒）
+  export_timeout: 30
+  # 最大队列大小（优先于 exporter.max_queue_size）
   max_queue_size: 2048
+  # 队列满时阻塞而不是丢弃 span（会拖慢业务，谨慎开启）
+  block_on_queue_full: false
 `
 }
 
@@ -622,18 +1584,69 @@ trace:
 
   # 采样配置
   sampler:
-    # 采样类型: always_on, never, traceid_ratio, parent_based
+    # 采样类型: always_on, never, traceid_ratio, parent_based, rate_limiting, rule_based
     type: always_on
     # 采样比率（0.0 - 1.0），仅当 type=traceid_ratio 时生效
     ratio: 1.0
+    # parent_based：有上游 trace 时跟随上游的采样决定，新 trace 使用 root 采样器
+    # 生产环境常用配置：尊重上游决定，新 trace 采样 10%
+    # root:
+    #   type: traceid_ratio  # always_on, never, traceid_ratio, rate_limiting
+    #   ratio: 0.1
+    # 上游已采样 / 未采样时的处理方式: always_on, never, root（使用 root 采样器重新决定）
+    # remote_parent_sampled: always_on
+    # remote_parent_not_sampled: never
+    # rate_limiting：每秒最多采样 traces_per_second 个新 trace（令牌桶），有上游 trace 时与 parent_based 相同
+    # 根 span 上记录实际采样概率 sampling.probability，追踪后端可以据此估算真实请求量
+    # traces_per_second: 100
+    # rule_based：按顺序匹配规则（span 名称、类型、创建时的属性），使用第一条匹配规则的采样率
+    # 有上游 trace 时与 parent_based 相同；修改后调用 zltrace.ReloadSampler() 可以不重启生效
+    # rules:
+    #   - name: health
+    #     span_name: "GET /health*"     # 通配符；正则表达式使用 span_name_regex
+    #     span_kind: server
+    #     ratio: 0                      # 不采样
+    #   - name: audit
+    #     attributes:
+    #       kafka.topic: "audit-*"
+    #     traces_per_second: 1          # 每秒最多 1 个 trace
+    # default:
+    #   ratio: 0.1                      # 其他请求采样 10%
 
   # Exporter 配置（决定追踪数据发送到哪里）
   exporter:
-    # 导出类型: otlp, stdout, none
+    # 导出类型: otlp, stdout, file, none
     # - otlp: 发送到追踪系统（SkyWalking、Jaeger 等）
     # - stdout: 输出到日志（降级模式，不发送到追踪系统）
+    # - file: 写入本地文件（每行一个 OTLP/JSON 格式的 span）
     # - none: 不发送追踪数据（只生成 trace_id）
     type: stdout
+    # 同时启用多个 exporter（配置后忽略 type），每个 exporter 使用独立的批处理队列
+    # types: [otlp, stdout]
+
+    # stdout 配置（type=stdout 时生效）
+    stdout:
+      # 日志级别: debug, info, warn, error
+      level: debug
+      # 只输出状态为 Error 的 span
+      only_errors: false
+      # 只输出耗时不小于该值（毫秒）的 span，0 表示不过滤
+      slow_threshold_ms: 0
+
+    # 文件配置（type=file 时生效）
+    file:
+      # 文件路径
+      path: ./logs/trace/spans.jsonl
+      # 单个文件的最大大小（MB），超过后切割
+      max_size: 100
+      # 保留的旧文件个数，0 表示不按个数清理
+      max_backups: 10
+      # 旧文件保留天数，0 表示不按天数清理
+      max_age: 7
+      # 是否压缩旧文件
+      compress: false
+      # 按时间切割的间隔（如 24h），0 表示只按大小切割
+      rotate_interval: 0
 
     # OTLP gRPC 配置（type=otlp 时生效）
     otlp:
@@ -643,17 +1656,75 @@ trace:
       timeout: 10
       # 是否使用 insecure 连接（开发环境）
       insecure: true
-
-    # 最大队列大小
+      # TLS 配置（insecure=false 时生效，不配置则使用系统根证书）
+      # tls:
+      #   ca_file: /etc/zltrace/ca.pem         # 校验服务端的 CA 证书
+      #   cert_file: /etc/zltrace/client.pem   # 客户端证书（双向 TLS）
+      #   key_file: /etc/zltrace/client.key    # 客户端私钥（双向 TLS）
+      #   server_name: collector.example.com   # 校验证书使用的域名
+      #   insecure_skip_verify: false          # 跳过证书校验（仅调试）
+      #   min_version: "1.2"                   # 最低 TLS 版本
+      # 传输协议: grpc（默认，端口 4317）, http/protobuf（端口 4318）
+      protocol: grpc
+      # HTTP 请求路径（仅 http/protobuf）
+      # url_path: /v1/traces
+      # 每次导出附带的请求头（例如鉴权 token）
+      # 值支持 ${env:NAME}（环境变量）和 file:/path（文件）引用，避免 token 明文写在配置中
+      # headers:
+      #   authorization: Bearer ${env:OTLP_TOKEN}
+      #   x-tenant-id: file:/run/secrets/tenant
+      # 压缩方式: none, gzip
+      compression: none
+
+    # 最大队列大小（已弃用，请使用 batch.max_queue_size）
     max_queue_size: 2048
 
+    # 导出失败时的重试配置（指数退避，时间需要带单位）
+    retry:
+      # 是否重试（关闭后失败的批次直接丢弃）
+      enabled: true
+      # 第一次重试前的等待时间
+      initial_interval: 5s
+      # 两次重试之间的最大等待时间
+      max_interval: 30s
+      # 一个批次重试的总时长上限
+      max_elapsed_time: 1m
+
+    # OTLP 不可用时自动降级到日志输出（使用上面的 stdout 配置）
+    failover:
+      # 是否开启自动降级
+      enabled: false
+      # 连续失败多少批后降级
+      failure_threshold: 3
+      # 降级期间探测 OTLP 的间隔
+      probe_interval: 30s
+
+    # OTLP 导出失败时缓存到磁盘，恢复后重放（不能与 failover 同时开启）
+    spool:
+      # 是否开启磁盘缓冲
+      enabled: false
+      # 磁盘队列目录
+      dir: ./data/trace-spool
+      # 磁盘队列的最大字节数（默认 100MB），超过后删除最旧的数据
+      max_bytes: 104857600
+      # 数据最长保留时间
+      max_age: 24h
+      # 每秒最多重放的 span 数量
+      replay_rate: 1000
+
   # 批量处理配置
   batch:
-    # 批量发送的最大 span 数量
+    # 批量发送的最大 span 数量（不能大于 max_queue_size）
     batch_size: 512
     # 批量发送的超时时间（秒）
     timeout: 5
-    # 最大队列大小
+    # 批量发送的超时时间（毫秒，配置后优先于 timeout）
+    # schedule_delay_ms: 5000
+    # 单次导出的超时时间（秒）
+    export_timeout: 30
+    # 最大队列大小（优先于 exporter.max_queue_size）
     max_queue_size: 2048
+    # 队列满时阻塞而不是丢弃 span（会拖慢业务，谨慎开启）
+    block_on_queue_full: false
 `
 }
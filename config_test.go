@@ -2,7 +2,11 @@ package zltrace
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -135,3 +139,394 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigLoaderTLS(t *testing.T) {
+	caFile := newTestCerts(t).caFile
+
+	traceYAML := `
+exporter:
+  type: otlp
+  otlp:
+    endpoint: collector:4317
+    insecure: false
+    tls:
+      ca_file: ` + caFile + `
+      server_name: collector.example.com
+      min_version: "1.3"
+`
+	appYAML := `
+trace:
+  exporter:
+    type: otlp
+    otlp:
+      endpoint: collector:4317
+      insecure: false
+      tls:
+        ca_file: ` + caFile + `
+        server_name: collector.example.com
+        min_version: "1.3"
+`
+	for _, f := range []struct{ name, content string }{
+		{"trace.yaml", traceYAML},
+		{"application.yaml", appYAML},
+	} {
+		t.Run(f.name, func(t *testing.T) {
+			configDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(configDir, f.name), []byte(f.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			loader := NewConfigLoader()
+			loader.SetConfigDirs(configDir)
+			config, err := loader.Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			tls := config.Exporter.OTLP.TLS
+			if tls.CAFile != caFile || tls.ServerName != "collector.example.com" || tls.MinVersion != "1.3" {
+				t.Errorf("unexpected tls config: %+v", tls)
+			}
+		})
+	}
+
+	// CA 证书无效时 Load 返回错误，不会退回默认配置
+	invalidCA := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(invalidCA, []byte("ca"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	configDir := t.TempDir()
+	content := strings.ReplaceAll(traceYAML, caFile, invalidCA)
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	if _, err := loader.Load(); err == nil || !strings.Contains(err.Error(), "trace.exporter.otlp.tls") {
+		t.Errorf("Load() error = %v, want invalid tls error", err)
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("ZLTRACE_TEST_TOKEN", "s3cret")
+	os.Unsetenv("ZLTRACE_TEST_MISSING")
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("  from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "plain", false},
+		{"${env:ZLTRACE_TEST_TOKEN}", "s3cret", false},
+		{"Bearer ${env:ZLTRACE_TEST_TOKEN}", "Bearer s3cret", false},
+		{"file:" + secretFile, "from-file", false},
+		{"${env:ZLTRACE_TEST_MISSING}", "", true},
+		{"file:" + filepath.Join(t.TempDir(), "missing"), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveSecret(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveSecret(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveSecret(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	// 无法解析的引用在配置校验阶段就报错
+	config := &TraceConfig{Enabled: true, Exporter: ExporterConfig{Type: "otlp", OTLP: OTLPConfig{
+		Endpoint: "localhost:4317",
+		Headers:  map[string]string{"authorization": "${env:ZLTRACE_TEST_MISSING}"},
+	}}}
+	if err := validateConfig(config); err == nil {
+		t.Error("validateConfig should reject unresolvable header secrets")
+	}
+}
+
+func TestConfigLoaderHeaders(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+trace:
+  exporter:
+    type: otlp
+    otlp:
+      endpoint: collector:4318
+      protocol: http/protobuf
+      headers:
+        Authorization: Bearer ${env:ZLTRACE_TEST_TOKEN}
+`
+	if err := os.WriteFile(filepath.Join(configDir, "application.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ZLTRACE_TEST_TOKEN", "s3cret")
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// 配置中保留引用本身，导出时才解析
+	if got := config.Exporter.OTLP.Headers["authorization"]; got != "Bearer ${env:ZLTRACE_TEST_TOKEN}" {
+		t.Errorf("authorization header = %q", got)
+	}
+	if config.Exporter.OTLP.Protocol != OTLPProtocolHTTPProtobuf {
+		t.Errorf("protocol = %s, want http/protobuf", config.Exporter.OTLP.Protocol)
+	}
+}
+
+func TestConfigLoaderRetry(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+exporter:
+  type: otlp
+  otlp:
+    endpoint: collector:4317
+    compression: gzip
+  retry:
+    enabled: true
+    initial_interval: 500ms
+    max_interval: 10s
+    max_elapsed_time: 2m
+`
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := RetryConfig{Enabled: true, InitialInterval: 500 * time.Millisecond, MaxInterval: 10 * time.Second, MaxElapsedTime: 2 * time.Minute}
+	if config.Exporter.Retry != want {
+		t.Errorf("retry = %+v, want %+v", config.Exporter.Retry, want)
+	}
+	if config.Exporter.OTLP.Compression != "gzip" {
+		t.Errorf("compression = %s, want gzip", config.Exporter.OTLP.Compression)
+	}
+}
+
+func TestConfigLoaderBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantQueue int
+		wantBatch int
+		wantErr   bool
+	}{
+		{
+			name: "batch queue size wins",
+			content: `
+exporter:
+  max_queue_size: 100
+batch:
+  max_queue_size: 4096
+  batch_size: 256
+  schedule_delay_ms: 200
+  export_timeout: 10
+  block_on_queue_full: true
+`,
+			wantQueue: 4096,
+			wantBatch: 256,
+		},
+		{
+			name: "deprecated exporter queue size",
+			content: `
+exporter:
+  max_queue_size: 1024
+`,
+			wantQueue: 1024,
+			wantBatch: 512,
+		},
+		{
+			name: "default batch size clamped to queue size",
+			content: `
+batch:
+  max_queue_size: 256
+`,
+			wantQueue: 256,
+			wantBatch: 256,
+		},
+		{
+			name: "explicit batch size greater than queue size",
+			content: `
+batch:
+  max_queue_size: 256
+  batch_size: 512
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			loader := NewConfigLoader()
+			loader.SetConfigDirs(configDir)
+			config, err := loader.Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if config.Batch.MaxQueueSize != tt.wantQueue {
+				t.Errorf("batch.max_queue_size = %d, want %d", config.Batch.MaxQueueSize, tt.wantQueue)
+			}
+			if config.Batch.BatchSize != tt.wantBatch {
+				t.Errorf("batch.batch_size = %d, want %d", config.Batch.BatchSize, tt.wantBatch)
+			}
+		})
+	}
+}
+
+func TestValidateBatchConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		batch   BatchConfig
+		wantErr bool
+	}{
+		{"default", BatchConfig{BatchSize: 512, Timeout: 5, MaxQueueSize: 2048}, false},
+		{"sdk defaults", BatchConfig{}, false},
+		{"batch size equals queue size", BatchConfig{BatchSize: 512, MaxQueueSize: 512}, false},
+		{"batch size greater than queue size", BatchConfig{BatchSize: 4096, MaxQueueSize: 2048}, true},
+		{"negative schedule delay", BatchConfig{ScheduleDelayMs: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &TraceConfig{Enabled: true, Exporter: ExporterConfig{Type: "stdout"}, Batch: tt.batch}
+			err := validateConfig(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigLoaderExporterTypes(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+exporter:
+  types: [otlp, stdout]
+  otlp:
+    endpoint: collector:4317
+`
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	types := config.Exporter.enabledTypes()
+	if len(types) != 2 || types[0] != "otlp" || types[1] != "stdout" {
+		t.Errorf("enabled types = %v, want [otlp stdout]", types)
+	}
+}
+
+func TestConfigLoaderSampler(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+sampler:
+  type: parent_based
+  root:
+    type: traceid_ratio
+    ratio: 0.1
+  remote_parent_not_sampled: root
+`
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := SamplerConfig{
+		Type:                   "parent_based",
+		Ratio:                  1.0,
+		Root:                   RootSamplerConfig{Type: "traceid_ratio", Ratio: 0.1},
+		RemoteParentNotSampled: "root",
+	}
+	if !reflect.DeepEqual(config.Sampler, want) {
+		t.Errorf("sampler = %+v, want %+v", config.Sampler, want)
+	}
+}
+
+func TestConfigLoaderInvalidConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"unknown sampler in trace.yaml", "trace.yaml", "sampler:\n  type: bogus\nexporter:\n  type: otlp\n  otlp:\n    endpoint: collector:4317\n"},
+		{"ratio out of range in trace.yaml", "trace.yaml", "sampler:\n  type: traceid_ratio\n  ratio: 1.5\n"},
+		{"unknown sampler in application.yaml", "application.yaml", "trace:\n  sampler:\n    type: bogus\n"},
+		{"malformed yaml", "trace.yaml", "sampler: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(configDir, tt.file), []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			loader := NewConfigLoader()
+			loader.SetConfigDirs(configDir)
+			loader.SetEnv("")
+			config, err := loader.Load()
+			if err == nil {
+				t.Fatalf("Load() should fail instead of falling back to defaults, got %+v", config)
+			}
+			if !strings.Contains(err.Error(), tt.file) {
+				t.Errorf("error should name the config file: %v", err)
+			}
+		})
+	}
+
+	// 没有配置文件时使用默认配置
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(t.TempDir())
+	loader.SetEnv("")
+	if config, err := loader.Load(); err != nil || config.Exporter.Type != "stdout" {
+		t.Errorf("Load() without config files: config = %+v, err = %v", config, err)
+	}
+
+	// 无法读取的 application.yaml 会被跳过
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "application.yaml"), []byte("server: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loader = NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	loader.SetEnv("")
+	if config, err := loader.Load(); err != nil || config.Exporter.Type != "stdout" {
+		t.Errorf("Load() with unreadable application.yaml: config = %+v, err = %v", config, err)
+	}
+}
@@ -0,0 +1,72 @@
+package zltrace
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/zlxdbj/zllog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ============================================================================
+// reportingExporter - 按 exporter 分别报告导出结果
+// ============================================================================
+
+// reportingExporter 包装 Exporter，导出失败时输出带 exporter 名称的错误日志
+//
+// 启用多个 exporter 时，每个 exporter 有自己的批处理器，
+// 一个 exporter 失败不影响其他 exporter；日志中的 exporter 字段用于区分是哪一个失败。
+// 连续失败后第一次成功时输出恢复日志。
+type reportingExporter struct {
+	name string
+	next sdktrace.SpanExporter
+
+	mu       sync.Mutex
+	failures int // 连续失败次数
+}
+
+// newReportingExporter 创建 reportingExporter
+func newReportingExporter(name string, next sdktrace.SpanExporter) *reportingExporter {
+	return &reportingExporter{name: name, next: next}
+}
+
+// ExportSpans 导出 span 并记录结果
+func (e *reportingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.next.ExportSpans(ctx, spans)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil {
+		e.failures++
+		zllog.Error(context.Background(), "trace.exporter", "导出 span 失败", err,
+			zllog.String("exporter", e.name),
+			zllog.Int("spans", len(spans)),
+			zllog.Int("consecutive_failures", e.failures))
+		return fmt.Errorf("%s exporter 导出失败: %w", e.name, err)
+	}
+
+	if e.failures > 0 {
+		zllog.Info(context.Background(), "trace.exporter", "导出 span 恢复正常",
+			zllog.String("exporter", e.name),
+			zllog.Int("previous_failures", e.failures))
+		e.failures = 0
+	}
+	return nil
+}
+
+// ForceFlush 刷新被包装的 Exporter（如果它有缓冲）
+func (e *reportingExporter) ForceFlush(ctx context.Context) error {
+	if flusher, ok := e.next.(interface {
+		ForceFlush(ctx context.Context) error
+	}); ok {
+		return flusher.ForceFlush(ctx)
+	}
+	return nil
+}
+
+// Shutdown 关闭被包装的 Exporter
+func (e *reportingExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
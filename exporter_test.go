@@ -0,0 +1,175 @@
+package zltrace
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zlxdbj/zllog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// failingExporter 总是导出失败的 Exporter
+type failingExporter struct{}
+
+func (failingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return errors.New("collector unavailable")
+}
+
+func (failingExporter) Shutdown(ctx context.Context) error { return nil }
+
+// blockingExporter 在 release 关闭前阻塞导出
+type blockingExporter struct {
+	release  chan struct{}
+	started  chan struct{} // 可选，开始导出时关闭
+	shutdown atomic.Bool
+}
+
+func (e *blockingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if e.started != nil {
+		close(e.started)
+	}
+	select {
+	case <-e.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *blockingExporter) Shutdown(ctx context.Context) error {
+	e.shutdown.Store(true)
+	return nil
+}
+
+func TestCreateExportersFanOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	config := &TraceConfig{Exporter: ExporterConfig{
+		Type:  "none",
+		Types: []string{"file", "stdout"},
+		File:  FileConfig{Path: path},
+	}}
+
+	exporters, err := createExporters(config)
+	if err != nil {
+		t.Fatalf("createExporters() error = %v", err)
+	}
+	if len(exporters) != 2 {
+		t.Fatalf("createExporters() returned %d exporters, want 2", len(exporters))
+	}
+
+	var opts []sdktrace.TracerProviderOption
+	for _, exporter := range exporters {
+		opts = append(opts, sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(time.Hour)))
+	}
+	tracer := newOTELTracer(sdktrace.NewTracerProvider(opts...), exporters, "test")
+
+	span, _ := tracer.StartSpan(context.Background(), "fan-out")
+	span.Finish()
+	if err := tracer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	spans, err := ReadSpanFile(path)
+	if err != nil || len(spans) != 1 {
+		t.Fatalf("file exporter spans = %d, err = %v", len(spans), err)
+	}
+
+	// none 不创建 exporter
+	exporters, err = createExporters(&TraceConfig{Exporter: ExporterConfig{Type: "none"}})
+	if err != nil || len(exporters) != 0 {
+		t.Errorf("none: exporters = %d, err = %v", len(exporters), err)
+	}
+}
+
+func TestFanOutSlowExporterDoesNotBlockOthers(t *testing.T) {
+	slow := &blockingExporter{release: make(chan struct{})}
+	memory := tracetest.NewInMemoryExporter()
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(newReportingExporter("otlp", slow), sdktrace.WithBatchTimeout(10*time.Millisecond)),
+		sdktrace.WithBatcher(newReportingExporter("stdout", memory), sdktrace.WithBatchTimeout(10*time.Millisecond)),
+	)
+	defer func() {
+		close(slow.release)
+		tp.Shutdown(context.Background())
+	}()
+
+	_, span := tp.Tracer("test").Start(context.Background(), "op")
+	span.End()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(memory.GetSpans()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(memory.GetSpans()) != 1 {
+		t.Error("span should reach the fast exporter while the slow one is blocked")
+	}
+}
+
+func TestReportingExporterReportsFailuresPerExporter(t *testing.T) {
+	original := zllog.GetLogger()
+	defer zllog.SetLogger(original)
+	recorder := &recordingLogger{}
+	zllog.SetLogger(recorder)
+
+	exporter := newReportingExporter("otlp", failingExporter{})
+	spans := tracetest.SpanStubs{{Name: "op"}}.Snapshots()
+
+	for i := 1; i <= 2; i++ {
+		if err := exporter.ExportSpans(context.Background(), spans); err == nil {
+			t.Fatal("ExportSpans() should return the wrapped error")
+		}
+		if recorder.level != "error" {
+			t.Fatalf("level = %s, want error", recorder.level)
+		}
+		if got, _ := recorder.field("exporter"); got != "otlp" {
+			t.Errorf("exporter = %v, want otlp", got)
+		}
+		if got, _ := recorder.field("consecutive_failures"); got != i {
+			t.Errorf("consecutive_failures = %v, want %d", got, i)
+		}
+	}
+
+	// 恢复后输出一次恢复日志，之后的成功不再输出
+	exporter.next = tracetest.NewInMemoryExporter()
+	count := recorder.count
+	exporter.ExportSpans(context.Background(), spans)
+	if recorder.count != count+1 || recorder.level != "info" {
+		t.Errorf("recovery should be logged once at info, level = %s", recorder.level)
+	}
+	exporter.ExportSpans(context.Background(), spans)
+	if recorder.count != count+1 {
+		t.Error("successful exports should not be logged")
+	}
+}
+
+func TestValidateExporterTypes(t *testing.T) {
+	otlp := OTLPConfig{Endpoint: "localhost:4317", Insecure: true}
+	tests := []struct {
+		name     string
+		exporter ExporterConfig
+		wantErr  bool
+	}{
+		{"single type", ExporterConfig{Type: "stdout"}, false},
+		{"types override type", ExporterConfig{Type: "invalid", Types: []string{"otlp", "stdout"}, OTLP: otlp}, false},
+		{"invalid type in list", ExporterConfig{Types: []string{"otlp", "kafka"}, OTLP: otlp}, true},
+		{"duplicate type", ExporterConfig{Types: []string{"stdout", "stdout"}}, true},
+		{"none with others", ExporterConfig{Types: []string{"none", "stdout"}}, true},
+		{"otlp in list without endpoint", ExporterConfig{Types: []string{"stdout", "otlp"}}, true},
+		{"file in list without path", ExporterConfig{Types: []string{"otlp", "file"}, OTLP: otlp}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(&TraceConfig{Enabled: true, Exporter: tt.exporter})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
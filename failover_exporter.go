@@ -0,0 +1,166 @@
+package zltrace
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zlxdbj/zllog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ============================================================================
+// FailoverExporter - 主 Exporter 不可用时自动降级
+// ============================================================================
+
+const (
+	defaultFailureThreshold = 3
+	defaultProbeInterval    = 30 * time.Second
+)
+
+// FailoverExporter 主 Exporter 连续失败后自动切换到备用 Exporter
+//
+// 典型用法是 OTLP 为主、LoggingExporter 为备：追踪系统故障期间 span 输出到日志而不是被丢弃。
+//   - 主 Exporter 导出失败的批次都由备用导出，不会丢弃
+//   - 主 Exporter 连续失败 failureThreshold 次后切换到备用，之后的批次不再发给主 Exporter
+//   - 降级期间每隔 probeInterval 用一批真实数据探测主 Exporter，成功后切换回主 Exporter
+//   - 每次切换输出一条 warn 日志
+//
+// 探测只在 ExportSpans 中进行，没有后台定时器：距上次探测超过 probeInterval 后，
+// 下一批数据先发给主 Exporter。没有新的 span 时不会探测，恢复时间取决于流量，
+// 长时间没有流量后，主 Exporter 在第一批数据到来时才被确认恢复（这一批失败时仍由备用导出，不会丢失）。
+// 不使用空批次做后台探测，是因为 OTLP Exporter 收到空批次时不会发起请求，无法判断主 Exporter 是否可用。
+//
+// 调用主 Exporter 时不持有锁，主 Exporter 等待重试时不会阻塞 Degraded() 等调用。
+//
+// 注意：主 Exporter 开启重试时，一批数据要等重试结束才算失败，
+// 需要更快降级时可以调小 exporter.retry.max_elapsed_time。
+type FailoverExporter struct {
+	primary  sdktrace.SpanExporter
+	fallback sdktrace.SpanExporter
+
+	failureThreshold int
+	probeInterval    time.Duration
+	now              func() time.Time // 测试时替换
+
+	mu        sync.Mutex
+	failures  int       // 主 Exporter 连续失败次数
+	degraded  bool      // 是否已切换到备用 Exporter
+	lastProbe time.Time // 上一次探测主 Exporter 的时间
+}
+
+// FailoverOption FailoverExporter 的可选参数
+type FailoverOption func(*FailoverExporter)
+
+// WithFailureThreshold 连续失败多少次后切换到备用 Exporter（默认 3）
+func WithFailureThreshold(n int) FailoverOption {
+	return func(e *FailoverExporter) {
+		if n > 0 {
+			e.failureThreshold = n
+		}
+	}
+}
+
+// WithProbeInterval 降级期间探测主 Exporter 的最小间隔（默认 30s），探测由导出触发，见 FailoverExporter
+func WithProbeInterval(interval time.Duration) FailoverOption {
+	return func(e *FailoverExporter) {
+		if interval > 0 {
+			e.probeInterval = interval
+		}
+	}
+}
+
+// NewFailoverExporter 创建 FailoverExporter
+func NewFailoverExporter(primary, fallback sdktrace.SpanExporter, opts ...FailoverOption) *FailoverExporter {
+	e := &FailoverExporter{
+		primary:          primary,
+		fallback:         fallback,
+		failureThreshold: defaultFailureThreshold,
+		probeInterval:    defaultProbeInterval,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Degraded 返回当前是否已切换到备用 Exporter
+func (e *FailoverExporter) Degraded() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.degraded
+}
+
+// ExportSpans 导出 span（实现 SpanExporter 接口）
+func (e *FailoverExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	if e.degraded {
+		if e.now().Sub(e.lastProbe) < e.probeInterval {
+			e.mu.Unlock()
+			return e.fallback.ExportSpans(ctx, spans)
+		}
+		// 先更新探测时间再释放锁，同一个探测间隔内只有一批数据发给主 Exporter
+		e.lastProbe = e.now()
+	}
+	e.mu.Unlock()
+
+	err := e.primary.ExportSpans(ctx, spans)
+
+	e.mu.Lock()
+	if err == nil {
+		recovered := e.degraded
+		e.degraded = false
+		e.failures = 0
+		e.mu.Unlock()
+		if recovered {
+			zllog.Warn(context.Background(), "trace.exporter", "主 Exporter 已恢复，切换回主 Exporter")
+		}
+		return nil
+	}
+
+	e.failures++
+	failures := e.failures
+	switched := !e.degraded && failures >= e.failureThreshold
+	if switched {
+		e.degraded = true
+		e.lastProbe = e.now()
+	}
+	e.mu.Unlock()
+
+	if switched {
+		zllog.Warn(context.Background(), "trace.exporter", "主 Exporter 连续导出失败，切换到备用 Exporter",
+			zllog.Int("consecutive_failures", failures),
+			zllog.String("probe_interval", e.probeInterval.String()),
+			zllog.String("error", err.Error()))
+	}
+	// 未达到阈值时同样由备用导出，这一批不会丢弃
+	return e.fallback.ExportSpans(ctx, spans)
+}
+
+// ForceFlush 刷新主、备 Exporter（如果它们有缓冲）
+func (e *FailoverExporter) ForceFlush(ctx context.Context) error {
+	for _, exporter := range []sdktrace.SpanExporter{e.primary, e.fallback} {
+		if flusher, ok := exporter.(interface {
+			ForceFlush(ctx context.Context) error
+		}); ok {
+			if err := flusher.ForceFlush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Shutdown 关闭主、备 Exporter
+func (e *FailoverExporter) Shutdown(ctx context.Context) error {
+	primaryErr := e.primary.Shutdown(ctx)
+	if err := e.fallback.Shutdown(ctx); err != nil {
+		return fmt.Errorf("关闭备用 Exporter 失败: %w", err)
+	}
+	if primaryErr != nil {
+		return fmt.Errorf("关闭主 Exporter 失败: %w", primaryErr)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package zltrace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zlxdbj/zllog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// switchableExporter 可以模拟不可用的 Exporter
+type switchableExporter struct {
+	mu       sync.Mutex
+	down     bool
+	attempts int
+	exported int
+}
+
+func (e *switchableExporter) setDown(down bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.down = down
+}
+
+func (e *switchableExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.attempts++
+	if e.down {
+		return errors.New("collector unavailable")
+	}
+	e.exported += len(spans)
+	return nil
+}
+
+func (e *switchableExporter) Shutdown(ctx context.Context) error { return nil }
+
+func TestFailoverExporter(t *testing.T) {
+	original := zllog.GetLogger()
+	defer zllog.SetLogger(original)
+	recorder := &recordingLogger{}
+	zllog.SetLogger(recorder)
+
+	primary := &switchableExporter{}
+	fallback := tracetest.NewInMemoryExporter()
+	now := time.Now()
+	exporter := NewFailoverExporter(primary, fallback, WithFailureThreshold(2), WithProbeInterval(time.Minute))
+	exporter.now = func() time.Time { return now }
+
+	spans := tracetest.SpanStubs{{Name: "op"}}.Snapshots()
+	export := func() error { return exporter.ExportSpans(context.Background(), spans) }
+
+	// 正常时只使用主 Exporter
+	if err := export(); err != nil || primary.exported != 1 || len(fallback.GetSpans()) != 0 {
+		t.Fatalf("healthy export: err = %v, primary = %d, fallback = %d", err, primary.exported, len(fallback.GetSpans()))
+	}
+
+	// 第一次失败：未达到阈值，不降级，但这一批由备用 Exporter 导出
+	primary.setDown(true)
+	logs := recorder.count
+	if err := export(); err != nil || exporter.Degraded() {
+		t.Fatalf("first failure: err = %v, degraded = %v", err, exporter.Degraded())
+	}
+	if len(fallback.GetSpans()) != 1 || recorder.count != logs {
+		t.Errorf("first failure: fallback spans = %d, want 1, logs = %d", len(fallback.GetSpans()), recorder.count-logs)
+	}
+
+	// 第二次失败：降级，这一批由备用 Exporter 导出
+	if err := export(); err != nil || !exporter.Degraded() {
+		t.Fatalf("second failure: err = %v, degraded = %v", err, exporter.Degraded())
+	}
+	if len(fallback.GetSpans()) != 2 {
+		t.Errorf("fallback spans = %d, want 2", len(fallback.GetSpans()))
+	}
+	if recorder.count != logs+1 || recorder.level != "warn" {
+		t.Errorf("switching to fallback should log a warning, level = %s", recorder.level)
+	}
+
+	// 探测间隔内不访问主 Exporter
+	attempts := primary.attempts
+	now = now.Add(30 * time.Second)
+	export()
+	if primary.attempts != attempts || len(fallback.GetSpans()) != 3 {
+		t.Errorf("primary should not be probed before the interval: attempts = %d", primary.attempts-attempts)
+	}
+
+	// 到达探测间隔但主 Exporter 仍不可用：继续降级
+	now = now.Add(time.Minute)
+	export()
+	if primary.attempts != attempts+1 || !exporter.Degraded() || len(fallback.GetSpans()) != 4 {
+		t.Errorf("failed probe: attempts = %d, degraded = %v", primary.attempts-attempts, exporter.Degraded())
+	}
+
+	// 主 Exporter 恢复：探测成功后切换回来
+	primary.setDown(false)
+	now = now.Add(time.Minute)
+	logs = recorder.count
+	if err := export(); err != nil || exporter.Degraded() {
+		t.Fatalf("recovery: err = %v, degraded = %v", err, exporter.Degraded())
+	}
+	if recorder.count != logs+1 || recorder.level != "warn" {
+		t.Errorf("switching back should log a warning, level = %s", recorder.level)
+	}
+	export()
+	if primary.exported != 3 || len(fallback.GetSpans()) != 4 {
+		t.Errorf("after recovery: primary = %d, fallback = %d", primary.exported, len(fallback.GetSpans()))
+	}
+}
+
+func TestFailoverExporterDoesNotHoldLockDuringExport(t *testing.T) {
+	primary := &blockingExporter{release: make(chan struct{}), started: make(chan struct{})}
+	exporter := NewFailoverExporter(primary, tracetest.NewInMemoryExporter())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- exporter.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "op"}}.Snapshots())
+	}()
+	<-primary.started
+
+	// 主 Exporter 阻塞期间 Degraded() 不应被阻塞
+	degraded := make(chan bool, 1)
+	go func() { degraded <- exporter.Degraded() }()
+	select {
+	case <-degraded:
+	case <-time.After(time.Second):
+		t.Fatal("Degraded() blocked while the primary exporter was exporting")
+	}
+
+	close(primary.release)
+	if err := <-done; err != nil {
+		t.Errorf("ExportSpans() error = %v", err)
+	}
+}
+
+func TestFailoverExporterFromConfig(t *testing.T) {
+	// 端口未监听：OTLP 导出失败后降级到 LoggingExporter
+	config := &TraceConfig{Exporter: ExporterConfig{
+		Type:     "otlp",
+		OTLP:     OTLPConfig{Endpoint: "127.0.0.1:1", Insecure: true, Timeout: 1},
+		Failover: FailoverConfig{Enabled: true, FailureThreshold: 1, ProbeInterval: time.Minute},
+	}}
+	exporter, err := createExporterByType(config, "otlp")
+	if err != nil {
+		t.Fatalf("createExporterByType() error = %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	failover, ok := exporter.(*FailoverExporter)
+	if !ok {
+		t.Fatalf("exporter = %T, want *FailoverExporter", exporter)
+	}
+	if _, ok := failover.fallback.(*LoggingExporter); !ok {
+		t.Errorf("fallback = %T, want *LoggingExporter", failover.fallback)
+	}
+	if err := exportOneSpan(exporter, 500*time.Millisecond); err != nil {
+		t.Errorf("degraded export should succeed, error = %v", err)
+	}
+	if !failover.Degraded() {
+		t.Error("exporter should be degraded after the collector refused the connection")
+	}
+}
+
+func TestConfigLoaderFailover(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+exporter:
+  type: otlp
+  otlp:
+    endpoint: collector:4317
+  failover:
+    enabled: true
+    failure_threshold: 5
+    probe_interval: 10s
+`
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := FailoverConfig{Enabled: true, FailureThreshold: 5, ProbeInterval: 10 * time.Second}
+	if config.Exporter.Failover != want {
+		t.Errorf("failover = %+v, want %+v", config.Exporter.Failover, want)
+	}
+
+	invalid := []FailoverConfig{
+		{Enabled: true, FailureThreshold: 0, ProbeInterval: time.Second},
+		{Enabled: true, FailureThreshold: 3, ProbeInterval: 30}, // 缺少单位
+	}
+	for _, failover := range invalid {
+		config := &TraceConfig{Enabled: true, Exporter: ExporterConfig{
+			Type:     "otlp",
+			OTLP:     OTLPConfig{Endpoint: "localhost:4317", Insecure: true},
+			Failover: failover,
+		}}
+		if err := validateConfig(config); err == nil {
+			t.Errorf("validateConfig() should reject %+v", failover)
+		}
+	}
+}
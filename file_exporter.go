@@ -0,0 +1,645 @@
+package zltrace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zlxdbj/zllog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// ============================================================================
+// FileExporter - 输出到本地文件的 Exporter（JSON Lines）
+// ============================================================================
+
+// FileExporter 将 span 写入本地文件，每行一个 span
+//
+// 每行是一个 OTLP/JSON 格式的 TracesData（只包含一个 span），
+// 可以直接交给 OpenTelemetry Collector 的 otlpjsonfile receiver 回放。
+// 文件按大小切割（配置 rotate_interval 时同时按时间切割），按个数和天数清理（基于 lumberjack）。
+//
+// 适用于批处理任务、无法连接追踪系统的隔离环境。
+// 每批 span 写完后都会刷新到文件；ForceFlush/Shutdown 时同样会刷新。
+type FileExporter struct {
+	mu      sync.Mutex
+	file    *lumberjack.Logger
+	writer  *bufio.Writer
+	stopped bool
+	written bool // 上次切割后是否写入过数据
+
+	// 按时间切割（rotate_interval > 0 时启动）
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFileExporter 创建文件 Exporter
+func NewFileExporter(config FileConfig) (*FileExporter, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("文件 Exporter 的路径不能为空")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建追踪文件目录失败: %w", err)
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   config.Path,
+		MaxSize:    config.MaxSize,
+		MaxBackups: config.MaxBackups,
+		MaxAge:     config.MaxAge,
+		Compress:   config.Compress,
+	}
+	e := &FileExporter{file: file, writer: bufio.NewWriter(file)}
+	if config.RotateInterval > 0 {
+		e.stop = make(chan struct{})
+		e.done = make(chan struct{})
+		go e.rotateLoop(config.RotateInterval)
+	}
+	return e, nil
+}
+
+// ExportSpans 将 span 逐行写入文件
+func (e *FileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return nil
+	}
+
+	for _, span := range spans {
+		line, err := json.Marshal(newJSONTracesData(span))
+		if err != nil {
+			return fmt.Errorf("序列化 span 失败: %w", err)
+		}
+		if _, err := e.writer.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("写入追踪文件失败: %w", err)
+		}
+		e.written = true
+	}
+	return e.flush()
+}
+
+// ForceFlush 将缓冲的数据写入文件
+func (e *FileExporter) ForceFlush(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return nil
+	}
+	return e.flush()
+}
+
+// Shutdown 停止按时间切割，刷新并关闭文件
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	if e.stop != nil {
+		e.stopOnce.Do(func() { close(e.stop) })
+		<-e.done
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return nil
+	}
+	e.stopped = true
+
+	flushErr := e.flush()
+	if err := e.file.Close(); err != nil {
+		return fmt.Errorf("关闭追踪文件失败: %w", err)
+	}
+	return flushErr
+}
+
+// rotateLoop 后台按固定间隔切割文件
+func (e *FileExporter) rotateLoop(interval time.Duration) {
+	defer close(e.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			if err := e.rotate(); err != nil {
+				zllog.Error(context.Background(), "trace.exporter", "按时间切割追踪文件失败", err)
+			}
+		}
+	}
+}
+
+// rotate 刷新缓冲区并切割文件；上次切割后没有写入数据时跳过，避免产生空文件
+func (e *FileExporter) rotate() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped || !e.written {
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	if err := e.file.Rotate(); err != nil {
+		return fmt.Errorf("切割追踪文件失败: %w", err)
+	}
+	e.written = false
+	return nil
+}
+
+// flush 刷新缓冲区（调用方持有锁）
+func (e *FileExporter) flush() error {
+	if err := e.writer.Flush(); err != nil {
+		return fmt.Errorf("刷新追踪文件失败: %w", err)
+	}
+	return nil
+}
+
+// ReadSpanFile 读取 FileExporter 写出的文件，还原为 span 数据
+//
+// 返回的 SpanStubs 可以通过 Snapshots() 转换为 ReadOnlySpan，
+// 用于测试断言或离线分析工具。空行会被跳过。
+//
+// 使用示例：
+//
+//	spans, err := zltrace.ReadSpanFile("/var/log/trace/spans.jsonl")
+//	for _, s := range spans {
+//	    fmt.Println(s.Name, s.EndTime.Sub(s.StartTime))
+//	}
+func ReadSpanFile(path string) (tracetest.SpanStubs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开追踪文件失败: %w", err)
+	}
+	defer f.Close()
+
+	var spans tracetest.SpanStubs
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var data jsonTracesData
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			return nil, fmt.Errorf("解析追踪文件第 %d 行失败: %w", lineNo, err)
+		}
+		stubs, err := data.spanStubs()
+		if err != nil {
+			return nil, fmt.Errorf("解析追踪文件第 %d 行失败: %w", lineNo, err)
+		}
+		spans = append(spans, stubs...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取追踪文件失败: %w", err)
+	}
+	return spans, nil
+}
+
+// ============================================================================
+// OTLP/JSON 编码
+// ============================================================================
+
+// OTLP/JSON 与 protobuf 的 JSON 映射基本一致，
+// 区别是 trace_id/span_id 使用十六进制字符串，枚举使用数值。
+// 64 位整数按 proto3 JSON 规范编码为字符串。
+
+type jsonTracesData struct {
+	ResourceSpans []jsonResourceSpans `json:"resourceSpans"`
+}
+
+type jsonResourceSpans struct {
+	Resource   jsonResource     `json:"resource"`
+	ScopeSpans []jsonScopeSpans `json:"scopeSpans"`
+	SchemaURL  string           `json:"schemaUrl,omitempty"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes,omitempty"`
+}
+
+type jsonScopeSpans struct {
+	Scope     jsonScope  `json:"scope"`
+	Spans     []jsonSpan `json:"spans"`
+	SchemaURL string     `json:"schemaUrl,omitempty"`
+}
+
+type jsonScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type jsonSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Flags             uint32         `json:"flags,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano jsonUint64     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   jsonUint64     `json:"endTimeUnixNano"`
+	Attributes        []jsonKeyValue `json:"attributes,omitempty"`
+	Events            []jsonEvent    `json:"events,omitempty"`
+	Links             []jsonLink     `json:"links,omitempty"`
+	Status            jsonStatus     `json:"status"`
+}
+
+type jsonEvent struct {
+	TimeUnixNano jsonUint64     `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []jsonKeyValue `json:"attributes,omitempty"`
+}
+
+type jsonLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	TraceState string         `json:"traceState,omitempty"`
+	Attributes []jsonKeyValue `json:"attributes,omitempty"`
+}
+
+type jsonStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *jsonInt64      `json:"intValue,omitempty"`
+	DoubleValue *jsonDouble     `json:"doubleValue,omitempty"`
+	ArrayValue  *jsonArrayValue `json:"arrayValue,omitempty"`
+}
+
+type jsonArrayValue struct {
+	Values []jsonAnyValue `json:"values"`
+}
+
+// jsonInt64 按 proto3 JSON 规范编码为字符串，解码时同时接受字符串和数字
+type jsonInt64 int64
+
+func (v jsonInt64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(v), 10))
+}
+
+func (v *jsonInt64) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseInt(unquoteJSONNumber(data), 10, 64)
+	*v = jsonInt64(n)
+	return err
+}
+
+// jsonUint64 按 proto3 JSON 规范编码为字符串，解码时同时接受字符串和数字
+type jsonUint64 uint64
+
+func (v jsonUint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(v), 10))
+}
+
+func (v *jsonUint64) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseUint(unquoteJSONNumber(data), 10, 64)
+	*v = jsonUint64(n)
+	return err
+}
+
+// jsonDouble 按 proto3 JSON 规范编码，NaN 和 ±Inf 编码为 "NaN"、"Infinity"、"-Infinity"
+//
+// encoding/json 不能编码非有限的浮点数，直接使用 float64 时一个 NaN 属性会导致整个 span 无法写出。
+type jsonDouble float64
+
+func (v jsonDouble) MarshalJSON() ([]byte, error) {
+	f := float64(v)
+	switch {
+	case math.IsNaN(f):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(f, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(f, -1):
+		return []byte(`"-Infinity"`), nil
+	}
+	return json.Marshal(f)
+}
+
+func (v *jsonDouble) UnmarshalJSON(data []byte) error {
+	switch s := unquoteJSONNumber(data); s {
+	case "NaN":
+		*v = jsonDouble(math.NaN())
+	case "Infinity":
+		*v = jsonDouble(math.Inf(1))
+	case "-Infinity":
+		*v = jsonDouble(math.Inf(-1))
+	default:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*v = jsonDouble(f)
+	}
+	return nil
+}
+
+func unquoteJSONNumber(data []byte) string {
+	s := string(data)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// OTLP 的状态码与 OTel Go 的 codes.Code 数值不同
+const (
+	otlpStatusUnset = 0
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// newJSONTracesData 将一个 span 编码为只包含它自己的 TracesData
+func newJSONTracesData(span sdktrace.ReadOnlySpan) jsonTracesData {
+	var res jsonResource
+	var schemaURL string
+	if r := span.Resource(); r != nil {
+		res.Attributes = toJSONKeyValues(r.Attributes())
+		schemaURL = r.SchemaURL()
+	}
+	scope := span.InstrumentationScope()
+
+	return jsonTracesData{ResourceSpans: []jsonResourceSpans{{
+		Resource:  res,
+		SchemaURL: schemaURL,
+		ScopeSpans: []jsonScopeSpans{{
+			Scope:     jsonScope{Name: scope.Name, Version: scope.Version},
+			Spans:     []jsonSpan{newJSONSpan(span)},
+			SchemaURL: scope.SchemaURL,
+		}},
+	}}}
+}
+
+func newJSONSpan(span sdktrace.ReadOnlySpan) jsonSpan {
+	sc := span.SpanContext()
+	s := jsonSpan{
+		TraceID:           sc.TraceID().String(),
+		SpanID:            sc.SpanID().String(),
+		TraceState:        sc.TraceState().String(),
+		Flags:             uint32(sc.TraceFlags()),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: jsonUint64(span.StartTime().UnixNano()),
+		EndTimeUnixNano:   jsonUint64(span.EndTime().UnixNano()),
+		Attributes:        toJSONKeyValues(span.Attributes()),
+		Status:            jsonStatus{Message: span.Status().Description},
+	}
+	if parent := span.Parent(); parent.IsValid() {
+		s.ParentSpanID = parent.SpanID().String()
+	}
+
+	switch span.Status().Code {
+	case codes.Ok:
+		s.Status.Code = otlpStatusOK
+	case codes.Error:
+		s.Status.Code = otlpStatusError
+	}
+
+	for _, event := range span.Events() {
+		s.Events = append(s.Events, jsonEvent{
+			TimeUnixNano: jsonUint64(event.Time.UnixNano()),
+			Name:         event.Name,
+			Attributes:   toJSONKeyValues(event.Attributes),
+		})
+	}
+	for _, link := range span.Links() {
+		s.Links = append(s.Links, jsonLink{
+			TraceID:    link.SpanContext.TraceID().String(),
+			SpanID:     link.SpanContext.SpanID().String(),
+			TraceState: link.SpanContext.TraceState().String(),
+			Attributes: toJSONKeyValues(link.Attributes),
+		})
+	}
+	return s
+}
+
+func toJSONKeyValues(attrs []attribute.KeyValue) []jsonKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]jsonKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		kvs = append(kvs, jsonKeyValue{Key: string(kv.Key), Value: toJSONAnyValue(kv.Value)})
+	}
+	return kvs
+}
+
+func toJSONAnyValue(v attribute.Value) jsonAnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return jsonAnyValue{BoolValue: &b}
+	case attribute.INT64:
+		i := jsonInt64(v.AsInt64())
+		return jsonAnyValue{IntValue: &i}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		d := jsonDouble(f)
+		return jsonAnyValue{DoubleValue: &d}
+	case attribute.BOOLSLICE:
+		var values []jsonAnyValue
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, toJSONAnyValue(attribute.BoolValue(b)))
+		}
+		return jsonAnyValue{ArrayValue: &jsonArrayValue{Values: values}}
+	case attribute.INT64SLICE:
+		var values []jsonAnyValue
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, toJSONAnyValue(attribute.Int64Value(i)))
+		}
+		return jsonAnyValue{ArrayValue: &jsonArrayValue{Values: values}}
+	case attribute.FLOAT64SLICE:
+		var values []jsonAnyValue
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, toJSONAnyValue(attribute.Float64Value(f)))
+		}
+		return jsonAnyValue{ArrayValue: &jsonArrayValue{Values: values}}
+	case attribute.STRINGSLICE:
+		var values []jsonAnyValue
+		for _, s := range v.AsStringSlice() {
+			values = append(values, toJSONAnyValue(attribute.StringValue(s)))
+		}
+		return jsonAnyValue{ArrayValue: &jsonArrayValue{Values: values}}
+	default:
+		s := v.Emit()
+		return jsonAnyValue{StringValue: &s}
+	}
+}
+
+// ============================================================================
+// OTLP/JSON 解码
+// ============================================================================
+
+// spanStubs 将 TracesData 还原为 span 数据
+func (d jsonTracesData) spanStubs() ([]tracetest.SpanStub, error) {
+	var stubs []tracetest.SpanStub
+	for _, rs := range d.ResourceSpans {
+		res := resource.NewWithAttributes(rs.SchemaURL, fromJSONKeyValues(rs.Resource.Attributes)...)
+		for _, ss := range rs.ScopeSpans {
+			scope := instrumentation.Scope{Name: ss.Scope.Name, Version: ss.Scope.Version, SchemaURL: ss.SchemaURL}
+			for _, s := range ss.Spans {
+				stub, err := s.spanStub()
+				if err != nil {
+					return nil, err
+				}
+				stub.Resource = res
+				stub.InstrumentationScope = scope
+				stubs = append(stubs, stub)
+			}
+		}
+	}
+	return stubs, nil
+}
+
+func (s jsonSpan) spanStub() (tracetest.SpanStub, error) {
+	sc, err := newSpanContextFromHex(s.TraceID, s.SpanID, s.TraceState, trace.TraceFlags(s.Flags))
+	if err != nil {
+		return tracetest.SpanStub{}, err
+	}
+
+	stub := tracetest.SpanStub{
+		Name:        s.Name,
+		SpanContext: sc,
+		SpanKind:    trace.SpanKind(s.Kind),
+		StartTime:   time.Unix(0, int64(s.StartTimeUnixNano)),
+		EndTime:     time.Unix(0, int64(s.EndTimeUnixNano)),
+		Attributes:  fromJSONKeyValues(s.Attributes),
+		Status:      sdktrace.Status{Description: s.Status.Message},
+	}
+	if s.ParentSpanID != "" {
+		parent, err := newSpanContextFromHex(s.TraceID, s.ParentSpanID, "", 0)
+		if err != nil {
+			return tracetest.SpanStub{}, err
+		}
+		stub.Parent = parent
+	}
+
+	switch s.Status.Code {
+	case otlpStatusOK:
+		stub.Status.Code = codes.Ok
+	case otlpStatusError:
+		stub.Status.Code = codes.Error
+	}
+
+	for _, e := range s.Events {
+		stub.Events = append(stub.Events, sdktrace.Event{
+			Name:       e.Name,
+			Time:       time.Unix(0, int64(e.TimeUnixNano)),
+			Attributes: fromJSONKeyValues(e.Attributes),
+		})
+	}
+	for _, l := range s.Links {
+		linkSC, err := newSpanContextFromHex(l.TraceID, l.SpanID, l.TraceState, 0)
+		if err != nil {
+			return tracetest.SpanStub{}, err
+		}
+		stub.Links = append(stub.Links, sdktrace.Link{SpanContext: linkSC, Attributes: fromJSONKeyValues(l.Attributes)})
+	}
+	return stub, nil
+}
+
+func newSpanContextFromHex(traceIDHex, spanIDHex, traceState string, flags trace.TraceFlags) (trace.SpanContext, error) {
+	traceID, err := trace.TraceIDFromHex(traceIDHex)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("无效的 traceId: %q", traceIDHex)
+	}
+	spanID, err := trace.SpanIDFromHex(spanIDHex)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("无效的 spanId: %q", spanIDHex)
+	}
+	config := trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: flags}
+	if traceState != "" {
+		ts, err := trace.ParseTraceState(traceState)
+		if err != nil {
+			return trace.SpanContext{}, fmt.Errorf("无效的 traceState: %w", err)
+		}
+		config.TraceState = ts
+	}
+	return trace.NewSpanContext(config), nil
+}
+
+func fromJSONKeyValues(kvs []jsonKeyValue) []attribute.KeyValue {
+	if len(kvs) == 0 {
+		return nil
+	}
+	attrs := make([]attribute.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		attrs = append(attrs, attribute.KeyValue{Key: attribute.Key(kv.Key), Value: fromJSONAnyValue(kv.Value)})
+	}
+	return attrs
+}
+
+func fromJSONAnyValue(v jsonAnyValue) attribute.Value {
+	switch {
+	case v.BoolValue != nil:
+		return attribute.BoolValue(*v.BoolValue)
+	case v.IntValue != nil:
+		return attribute.Int64Value(int64(*v.IntValue))
+	case v.DoubleValue != nil:
+		return attribute.Float64Value(float64(*v.DoubleValue))
+	case v.StringValue != nil:
+		return attribute.StringValue(*v.StringValue)
+	case v.ArrayValue != nil:
+		return fromJSONArrayValue(v.ArrayValue.Values)
+	default:
+		return attribute.StringValue("")
+	}
+}
+
+// fromJSONArrayValue 还原数组属性（OTel 属性只支持同类型数组，按第一个元素的类型还原）
+func fromJSONArrayValue(values []jsonAnyValue) attribute.Value {
+	if len(values) == 0 {
+		return attribute.StringSliceValue(nil)
+	}
+	switch {
+	case values[0].BoolValue != nil:
+		s := make([]bool, 0, len(values))
+		for _, v := range values {
+			s = append(s, fromJSONAnyValue(v).AsBool())
+		}
+		return attribute.BoolSliceValue(s)
+	case values[0].IntValue != nil:
+		s := make([]int64, 0, len(values))
+		for _, v := range values {
+			s = append(s, fromJSONAnyValue(v).AsInt64())
+		}
+		return attribute.Int64SliceValue(s)
+	case values[0].DoubleValue != nil:
+		s := make([]float64, 0, len(values))
+		for _, v := range values {
+			s = append(s, fromJSONAnyValue(v).AsFloat64())
+		}
+		return attribute.Float64SliceValue(s)
+	default:
+		s := make([]string, 0, len(values))
+		for _, v := range values {
+			s = append(s, fromJSONAnyValue(v).AsString())
+		}
+		return attribute.StringSliceValue(s)
+	}
+}
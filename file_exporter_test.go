@@ -0,0 +1,374 @@
+package zltrace
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newFileTracerProvider 创建同步写入 FileExporter 的 TracerProvider
+func newFileTracerProvider(t *testing.T, config FileConfig) (*sdktrace.TracerProvider, *FileExporter) {
+	t.Helper()
+	exporter, err := NewFileExporter(config)
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "file-test"))),
+	)
+	return tp, exporter
+}
+
+func TestFileExporterRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace", "spans.jsonl")
+	tp, _ := newFileTracerProvider(t, FileConfig{Path: path})
+	tracer := tp.Tracer("zltrace-test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "child", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithLinks(trace.Link{SpanContext: parent.SpanContext(), Attributes: []attribute.KeyValue{attribute.String("link", "parent")}}))
+	child.SetAttributes(
+		attribute.String("db.system", "mysql"),
+		attribute.Int64("db.rows", 42),
+		attribute.Float64("ratio", 0.5),
+		attribute.Bool("cached", true),
+		attribute.StringSlice("tags", []string{"a", "b"}),
+		attribute.Int64Slice("ids", []int64{1, 2}),
+	)
+	child.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", 2)))
+	child.SetStatus(codes.Error, "timeout")
+	child.End()
+	parent.SetStatus(codes.Ok, "")
+	parent.End()
+
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	spans, err := ReadSpanFile(path)
+	if err != nil {
+		t.Fatalf("ReadSpanFile() error = %v", err)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("read %d spans, want 2", len(spans))
+	}
+
+	got, gotParent := spans[0], spans[1]
+	if got.Name != "child" || gotParent.Name != "parent" {
+		t.Fatalf("span names = %s, %s", got.Name, gotParent.Name)
+	}
+	if got.SpanContext.TraceID() != parent.SpanContext().TraceID() || got.SpanContext.SpanID() != child.SpanContext().SpanID() {
+		t.Error("trace_id/span_id should round-trip")
+	}
+	if !got.SpanContext.IsSampled() {
+		t.Error("trace flags should round-trip")
+	}
+	if got.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("parent span_id should round-trip")
+	}
+	if got.SpanKind != trace.SpanKindClient || gotParent.SpanKind != trace.SpanKindServer {
+		t.Errorf("span kinds = %v, %v", got.SpanKind, gotParent.SpanKind)
+	}
+	if got.Status.Code != codes.Error || got.Status.Description != "timeout" || gotParent.Status.Code != codes.Ok {
+		t.Errorf("status = %+v, parent status = %+v", got.Status, gotParent.Status)
+	}
+	if got.EndTime.Sub(got.StartTime) <= 0 {
+		t.Error("start/end time should round-trip")
+	}
+
+	want := []attribute.KeyValue{
+		attribute.String("db.system", "mysql"),
+		attribute.Int64("db.rows", 42),
+		attribute.Float64("ratio", 0.5),
+		attribute.Bool("cached", true),
+		attribute.StringSlice("tags", []string{"a", "b"}),
+		attribute.Int64Slice("ids", []int64{1, 2}),
+	}
+	if len(got.Attributes) != len(want) {
+		t.Fatalf("attributes = %v, want %v", got.Attributes, want)
+	}
+	for i := range want {
+		if got.Attributes[i] != want[i] {
+			t.Errorf("attribute[%d] = %v, want %v", i, got.Attributes[i], want[i])
+		}
+	}
+
+	if len(got.Events) != 1 || got.Events[0].Name != "retry" || got.Events[0].Attributes[0].Value.AsInt64() != 2 {
+		t.Errorf("events = %+v", got.Events)
+	}
+	if len(got.Links) != 1 || got.Links[0].SpanContext.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("links = %+v", got.Links)
+	}
+	if v, ok := got.Resource.Set().Value("service.name"); !ok || v.AsString() != "file-test" {
+		t.Errorf("resource service.name = %v", v)
+	}
+	if got.InstrumentationScope.Name != "zltrace-test" {
+		t.Errorf("scope = %+v", got.InstrumentationScope)
+	}
+
+	// 读回的数据可以直接作为 ReadOnlySpan 使用
+	if snapshots := spans.Snapshots(); snapshots[0].Name() != "child" {
+		t.Error("Snapshots() should convert stubs to read-only spans")
+	}
+}
+
+func TestFileExporterOTLPJSONFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	tp, _ := newFileTracerProvider(t, FileConfig{Path: path})
+	_, span := tp.Tracer("test").Start(context.Background(), "op")
+	span.SetAttributes(attribute.Int64("count", 7))
+	span.SetStatus(codes.Error, "failed")
+	span.End()
+	tp.Shutdown(context.Background())
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(data)
+	if strings.Count(line, "\n") != 1 {
+		t.Fatalf("want one line per span, got %q", line)
+	}
+	for _, want := range []string{
+		`"resourceSpans":[`,
+		`"scopeSpans":[`,
+		`"traceId":"` + span.SpanContext().TraceID().String() + `"`,
+		`"intValue":"7"`,
+		`"status":{"message":"failed","code":2}`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("line should contain %s: %s", want, line)
+		}
+	}
+}
+
+func TestFileExporterNonFiniteFloat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	tp, _ := newFileTracerProvider(t, FileConfig{Path: path})
+
+	_, span := tp.Tracer("test").Start(context.Background(), "op")
+	span.SetAttributes(
+		attribute.Float64("nan", math.NaN()),
+		attribute.Float64("inf", math.Inf(1)),
+		attribute.Float64("neg_inf", math.Inf(-1)),
+		attribute.Float64Slice("values", []float64{1.5, math.NaN()}),
+	)
+	span.End()
+	_, next := tp.Tracer("test").Start(context.Background(), "next")
+	next.End()
+	tp.Shutdown(context.Background())
+
+	spans, err := ReadSpanFile(path)
+	if err != nil {
+		t.Fatalf("ReadSpanFile() error = %v", err)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans[0].Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if !math.IsNaN(attrs["nan"].AsFloat64()) || !math.IsInf(attrs["inf"].AsFloat64(), 1) || !math.IsInf(attrs["neg_inf"].AsFloat64(), -1) {
+		t.Errorf("non-finite attributes = %v", spans[0].Attributes)
+	}
+	if values := attrs["values"].AsFloat64Slice(); len(values) != 2 || values[0] != 1.5 || !math.IsNaN(values[1]) {
+		t.Errorf("values = %v", values)
+	}
+}
+
+func TestFileExporterRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "spans.jsonl")
+	tp, _ := newFileTracerProvider(t, FileConfig{Path: path, MaxSize: 1, MaxBackups: 1})
+	tracer := tp.Tracer("test")
+
+	// 每个 span 约 10KB，写满 1MB 触发切割
+	payload := strings.Repeat("x", 10*1024)
+	for i := 0; i < 250; i++ {
+		_, span := tracer.Start(context.Background(), "op")
+		span.SetAttributes(attribute.String("payload", payload))
+		span.End()
+	}
+	tp.Shutdown(context.Background())
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("expected rotated backup files, got %d files", len(entries))
+	}
+
+	total := 0
+	for _, entry := range entries {
+		spans, err := ReadSpanFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("ReadSpanFile(%s) error = %v", entry.Name(), err)
+		}
+		total += len(spans)
+	}
+	if total == 0 || total > 250 {
+		t.Errorf("read %d spans across rotated files", total)
+	}
+}
+
+func TestFileExporterRotateInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "spans.jsonl")
+	exporter, err := NewFileExporter(FileConfig{Path: path, RotateInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waitFiles 等待目录中的文件数达到 n（当前文件 + 切割出的旧文件）
+	waitFiles := func(n int) []os.DirEntry {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) >= n || time.Now().After(deadline) {
+				return entries
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if err := exportOneSpan(exporter, time.Second); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+	if entries := waitFiles(2); len(entries) != 2 {
+		t.Fatalf("files = %d, want the current file and one rotated backup", len(entries))
+	}
+
+	// 没有新数据时不再切割
+	time.Sleep(100 * time.Millisecond)
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("files = %d after idle intervals, want no empty backups", len(entries))
+	}
+
+	if err := exportOneSpan(exporter, time.Second); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	total := 0
+	for _, entry := range entries {
+		spans, err := ReadSpanFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("ReadSpanFile(%s) error = %v", entry.Name(), err)
+		}
+		total += len(spans)
+	}
+	if total != 2 {
+		t.Errorf("read %d spans across %d files, want 2", total, len(entries))
+	}
+
+	// Shutdown 后停止切割
+	time.Sleep(100 * time.Millisecond)
+	if after, _ := os.ReadDir(dir); len(after) != len(entries) {
+		t.Errorf("files changed after Shutdown: %d -> %d", len(entries), len(after))
+	}
+}
+
+func TestFileExporterForceFlushAndShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileExporter(FileConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := exportOneSpan(exporter, time.Second); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+	if err := exporter.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+	if spans, err := ReadSpanFile(path); err != nil || len(spans) != 1 {
+		t.Fatalf("after ForceFlush: spans = %d, err = %v", len(spans), err)
+	}
+
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	// 关闭后的导出被忽略
+	if err := exportOneSpan(exporter, time.Second); err != nil {
+		t.Fatalf("ExportSpans() after Shutdown error = %v", err)
+	}
+	if spans, _ := ReadSpanFile(path); len(spans) != 1 {
+		t.Errorf("spans after Shutdown = %d, want 1", len(spans))
+	}
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown() error = %v", err)
+	}
+}
+
+func TestReadSpanFileInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadSpanFile(filepath.Join(dir, "missing.jsonl")); err == nil {
+		t.Error("missing file should fail")
+	}
+
+	path := filepath.Join(dir, "bad.jsonl")
+	os.WriteFile(path, []byte("{\"resourceSpans\":[]}\n\nnot json\n"), 0o600)
+	if _, err := ReadSpanFile(path); err == nil || !strings.Contains(err.Error(), "第 3 行") {
+		t.Errorf("ReadSpanFile() error = %v, want line 3 error", err)
+	}
+}
+
+func TestFileExporterConfig(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+exporter:
+  type: file
+  file:
+    path: /var/log/trace/spans.jsonl
+    max_size: 50
+    max_backups: 3
+    max_age: 7
+    compress: true
+    rotate_interval: 24h
+`
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := FileConfig{Path: "/var/log/trace/spans.jsonl", MaxSize: 50, MaxBackups: 3, MaxAge: 7, Compress: true, RotateInterval: 24 * time.Hour}
+	if config.Exporter.Type != "file" || config.Exporter.File != want {
+		t.Errorf("exporter = %s %+v, want file %+v", config.Exporter.Type, config.Exporter.File, want)
+	}
+
+	if err := validateConfig(&TraceConfig{Enabled: true, Exporter: ExporterConfig{Type: "file"}}); err == nil {
+		t.Error("file exporter without path should be rejected")
+	}
+	if err := validateConfig(&TraceConfig{Enabled: true, Exporter: ExporterConfig{Type: "file", File: FileConfig{Path: "x", MaxAge: -1}}}); err == nil {
+		t.Error("negative max_age should be rejected")
+	}
+	// 没有单位的数字被解析为纳秒
+	if err := validateConfig(&TraceConfig{Enabled: true, Exporter: ExporterConfig{Type: "file", File: FileConfig{Path: "x", RotateInterval: 24}}}); err == nil {
+		t.Error("rotate_interval without unit should be rejected")
+	}
+}
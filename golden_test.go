@@ -0,0 +1,64 @@
+package zltrace_test
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/zlxdbj/zltrace"
+	"github.com/zlxdbj/zltrace/zltracetest"
+)
+
+// goldenHTTPHandler 实现 HTTPTraceHandler 和 HTTPStatusHandler 接口
+type goldenHTTPHandler struct {
+	method     string
+	url        string
+	headers    map[string]string
+	statusCode int
+	ctx        context.Context
+}
+
+func (h *goldenHTTPHandler) GetMethod() string                  { return h.method }
+func (h *goldenHTTPHandler) GetURL() string                     { return h.url }
+func (h *goldenHTTPHandler) GetHeader(key string) string        { return h.headers[key] }
+func (h *goldenHTTPHandler) SetSpanContext(ctx context.Context) { h.ctx = ctx }
+func (h *goldenHTTPHandler) GetSpanContext() context.Context    { return h.ctx }
+func (h *goldenHTTPHandler) GetStatusCode() int                 { return h.statusCode }
+
+var update = flag.Bool("update", false, "update golden trace files in testdata/")
+
+// 更新 golden 文件：go test -run TestTraceHTTPRequestGolden -update
+func TestTraceHTTPRequestGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler *goldenHTTPHandler
+	}{
+		{
+			name:    "trace_http_request",
+			handler: &goldenHTTPHandler{method: "GET", url: "/orders/1", statusCode: 200},
+		},
+		{
+			name: "trace_http_request_upstream_error",
+			handler: &goldenHTTPHandler{
+				method:     "POST",
+				url:        "/orders",
+				headers:    map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929b0e0e4736-00f067aa0ba902b7-01"},
+				statusCode: 503,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := zltracetest.New(t)
+
+			zltrace.TraceHTTPRequest(context.Background(), tt.handler, func() {
+				span, _ := zltrace.StartSpanWithKind(zltrace.GetSafeTracer(), tt.handler.GetSpanContext(), "db.query", zltrace.SpanKindClient)
+				span.SetTag("db.table", "orders")
+				span.Finish()
+			})
+
+			rec.AssertTree(t, tt.name, zltracetest.Update(*update))
+		})
+	}
+}
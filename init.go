@@ -111,6 +111,16 @@ func (t *noOpTracer) StartSpan(ctx context.Context, operationName string) (Span,
 	return &noOpSpan{}, ctx
 }
 
+// StartSpanWithKind 启动指定类型的 span（空操作）
+func (t *noOpTracer) StartSpanWithKind(ctx context.Context, operationName string, kind SpanKind) (Span, context.Context) {
+	return &noOpSpan{}, ctx
+}
+
+// StartSpanWithOptions 使用可选参数启动 span（空操作）
+func (t *noOpTracer) StartSpanWithOptions(ctx context.Context, operationName string, opts ...StartSpanOption) (Span, context.Context) {
+	return &noOpSpan{}, ctx
+}
+
 // Inject 注入 trace 上下文（空操作）
 func (t *noOpTracer) Inject(ctx context.Context, carrier Carrier) error {
 	return nil
@@ -137,8 +147,17 @@ func (s *noOpSpan) Context() context.Context {
 // SetTag 设置标签（空操作）
 func (s *noOpSpan) SetTag(key string, value interface{}) {}
 
+// SetTags 批量设置标签（空操作）
+func (s *noOpSpan) SetTags(attrs ...Attribute) {}
+
 // SetError 设置错误（空操作）
-func (s *noOpSpan) SetError(err error) {}
+func (s *noOpSpan) SetError(err error, opts ...ErrorOption) {}
+
+// AddEvent 添加事件（空操作）
+func (s *noOpSpan) AddEvent(name string, attrs ...Attribute) {}
+
+// SetStatus 设置状态（空操作）
+func (s *noOpSpan) SetStatus(code StatusCode, description string) {}
 
 // Finish 结束 span（空操作）
 func (s *noOpSpan) Finish() {}
@@ -148,6 +167,26 @@ func (s *noOpSpan) TraceID() string {
 	return ""
 }
 
+// SpanID 返回 span_id（空字符串）
+func (s *noOpSpan) SpanID() string {
+	return ""
+}
+
+// IsRecording 是否正在记录（始终为 false）
+func (s *noOpSpan) IsRecording() bool {
+	return false
+}
+
+// IsSampled 是否被采样（始终为 false）
+func (s *noOpSpan) IsSampled() bool {
+	return false
+}
+
+// SpanContext 返回 span 上下文（无效值）
+func (s *noOpSpan) SpanContext() SpanContext {
+	return SpanContext{}
+}
+
 // ============================================================================
 // HTTP 中间件获取函数
 // ============================================================================
@@ -167,8 +206,24 @@ type HTTPTraceHandler interface {
 	GetSpanContext() context.Context
 }
 
+// HTTPStatusHandler 可以获取响应状态码的HTTP追踪处理器（可选接口）
+// 实现后 TraceHTTPRequest 会记录 http.status_code，并将 5xx 响应标记为错误
+type HTTPStatusHandler interface {
+	// GetStatusCode 获取响应状态码（在 next 执行完之后调用）
+	GetStatusCode() int
+}
+
+// HTTPRouteHandler 可以获取路由模板的HTTP追踪处理器（可选接口）
+// 实现后 TraceHTTPRequest 在创建 span 时记录 http.route，采样规则可以按路由匹配
+type HTTPRouteHandler interface {
+	// GetRoute 获取匹配的路由模板，例如 /api/pay/:id；没有匹配的路由时返回空字符串
+	GetRoute() string
+}
+
 // TraceHTTPRequest 通用HTTP请求追踪函数
 // 框架无关，可以在任何HTTP框架的中间件中调用
+//
+// 状态映射规则（服务端）：5xx 标记为 Error，其余保持 Unset（4xx 是调用方的问题）
 // 使用示例：
 //
 //	func MyMiddleware(ctx context.Context, handler HTTPTraceHandler, next func()) {
@@ -187,8 +242,20 @@ func TraceHTTPRequest(ctx context.Context, handler HTTPTraceHandler, next func()
 	extractedCtx, _ := tracer.Extract(ctx, carrier)
 
 	// 创建Entry Span（如果有上游trace则继承，否则生成新的）
+	// 属性在创建 span 时传入，采样器（如 rule_based）可以看到
 	operationName := handler.GetMethod() + " " + handler.GetURL()
-	span, spanCtx := tracer.StartSpan(extractedCtx, operationName)
+	attrs := []Attribute{
+		Attr("http.method", handler.GetMethod()),
+		Attr("http.url", handler.GetURL()),
+	}
+	if routeHandler, ok := handler.(HTTPRouteHandler); ok {
+		if route := routeHandler.GetRoute(); route != "" {
+			attrs = append(attrs, Attr("http.route", route))
+		}
+	}
+	span, spanCtx := StartSpanWithOptions(tracer, extractedCtx, operationName,
+		WithSpanKind(SpanKindServer),
+		WithAttributes(attrs...))
 
 	// 将span注入到context
 	handler.SetSpanContext(spanCtx)
@@ -196,6 +263,15 @@ func TraceHTTPRequest(ctx context.Context, handler HTTPTraceHandler, next func()
 	// 调用下一个处理器
 	next()
 
+	// 记录响应状态码（服务端只有 5xx 视为错误）
+	if statusHandler, ok := handler.(HTTPStatusHandler); ok {
+		statusCode := statusHandler.GetStatusCode()
+		span.SetTag("http.status_code", statusCode)
+		if statusCode >= 500 {
+			span.SetStatus(StatusError, fmt.Sprintf("HTTP %d", statusCode))
+		}
+	}
+
 	// 结束span
 	span.Finish()
 }
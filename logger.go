@@ -0,0 +1,85 @@
+package zltrace
+
+import (
+	"context"
+
+	"github.com/zlxdbj/zllog"
+)
+
+// ============================================================================
+// 日志关联 - span_id
+// ============================================================================
+
+// spanIDLogger 包装 zllog.Logger，在每条日志中追加 span_id 字段
+//
+// zllog 的 TraceIDProvider 只负责 trace_id，span_id 由这里补充。
+// context 中没有有效 span 时不追加字段。
+type spanIDLogger struct {
+	next zllog.Logger
+}
+
+// InstallSpanIDLogger 包装当前的 zllog Logger，使日志同时携带 span_id
+//
+// InitOpenTelemetryTracer 会自动调用此函数。
+// 注意：zllog.InitLogger 会重新创建全局 Logger，
+// 因此应当先初始化日志系统，再调用 zltrace.InitTracer()；
+// 如果顺序相反，可以在 InitLogger 之后再调用一次本函数。
+// 重复调用是安全的，不会重复包装。
+func InstallSpanIDLogger() {
+	current := zllog.GetLogger()
+	if _, ok := current.(*spanIDLogger); ok {
+		return
+	}
+	if current == nil {
+		current = zllog.NewZerologLogger(zllog.GetGlobalLogger())
+	}
+	zllog.SetLogger(&spanIDLogger{next: current})
+}
+
+// withSpanID 在 fields 末尾追加 span_id（如果存在）
+func withSpanID(ctx context.Context, fields []zllog.Field) []zllog.Field {
+	if ctx == nil {
+		return fields
+	}
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return fields
+	}
+	spanID := span.SpanID()
+	if spanID == "" {
+		return fields
+	}
+	return append(fields[:len(fields):len(fields)], zllog.String("span_id", spanID))
+}
+
+func (l *spanIDLogger) Debug(ctx context.Context, module, message string, fields ...zllog.Field) {
+	l.next.Debug(ctx, module, message, withSpanID(ctx, fields)...)
+}
+
+func (l *spanIDLogger) Info(ctx context.Context, module, message string, fields ...zllog.Field) {
+	l.next.Info(ctx, module, message, withSpanID(ctx, fields)...)
+}
+
+func (l *spanIDLogger) Warn(ctx context.Context, module, message string, fields ...zllog.Field) {
+	l.next.Warn(ctx, module, message, withSpanID(ctx, fields)...)
+}
+
+func (l *spanIDLogger) Error(ctx context.Context, module, message string, err error, fields ...zllog.Field) {
+	l.next.Error(ctx, module, message, err, withSpanID(ctx, fields)...)
+}
+
+func (l *spanIDLogger) ErrorWithCode(ctx context.Context, module, message, errorCode string, err error, fields ...zllog.Field) {
+	l.next.ErrorWithCode(ctx, module, message, errorCode, err, withSpanID(ctx, fields)...)
+}
+
+func (l *spanIDLogger) Fatal(ctx context.Context, module, message string, err error, fields ...zllog.Field) {
+	l.next.Fatal(ctx, module, message, err, withSpanID(ctx, fields)...)
+}
+
+func (l *spanIDLogger) InfoWithRequest(ctx context.Context, module, message, requestID string, costMs int64, fields ...zllog.Field) {
+	l.next.InfoWithRequest(ctx, module, message, requestID, costMs, withSpanID(ctx, fields)...)
+}
+
+func (l *spanIDLogger) ErrorWithRequest(ctx context.Context, module, message, requestID string, err error, costMs int64, fields ...zllog.Field) {
+	l.next.ErrorWithRequest(ctx, module, message, requestID, err, costMs, withSpanID(ctx, fields)...)
+}
@@ -3,37 +3,138 @@ package zltrace
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/zlxdbj/zllog"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/semconv/v1.24.0"
 	"go.opentelemetry.io/otel/trace"
-	"github.com/zlxdbj/zllog"
+	"google.golang.org/grpc/credentials"
 )
 
 // ============================================================================
 // OTELTracer - OpenTelemetry 追踪器实现
 // ============================================================================
 
+// defaultShutdownTimeout Close 时等待缓冲 span 导出的最长时间
+const defaultShutdownTimeout = 5 * time.Second
+
 // OTELTracer 使用 OpenTelemetry 实现 Tracer 接口
 // 支持 W3C Trace Context 标准（traceparent header）
+//
+// OTELTracer 持有自己创建的 TracerProvider 和 Exporter，
+// 负责在 Shutdown/Close 时把批处理器中缓冲的 span 导出后再释放资源。
 type OTELTracer struct {
 	tracer     trace.Tracer
 	propagator propagation.TextMapPropagator
+
+	// provider 和 exporters 由 OTELTracer 负责关闭（exporters 可能为空，对应 exporter.type=none）
+	provider  *sdktrace.TracerProvider
+	exporters []sdktrace.SpanExporter
+
+	// sampler 可热更新的采样器（只有 InitOpenTelemetryTracer 创建的 Tracer 才有）
+	sampler *reloadableSampler
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// newOTELTracer 基于 TracerProvider 创建 OTELTracer
+func newOTELTracer(tp *sdktrace.TracerProvider, exporters []sdktrace.SpanExporter, name string) *OTELTracer {
+	return &OTELTracer{
+		tracer:     tp.Tracer(name),
+		propagator: propagation.TraceContext{}, // W3C Trace Context
+		provider:   tp,
+		exporters:  exporters,
+	}
+}
+
+// NewOTELTracer 基于调用方创建的 TracerProvider 创建 OTELTracer
+//
+// 用于自定义 TracerProvider 的场景，例如测试中使用内存 Exporter（参见 zltracetest 包）。
+// 注册后由 OTELTracer 负责关闭 tp；tp 上的 Exporter 由 tp 关闭。
+//
+// 使用示例：
+//
+//	exporter := tracetest.NewInMemoryExporter()
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+//	zltrace.RegisterTracer(zltrace.NewOTELTracer(tp, "my-service"))
+func NewOTELTracer(tp *sdktrace.TracerProvider, name string) *OTELTracer {
+	return newOTELTracer(tp, nil, name)
 }
 
 // StartSpan 启动一个新的 span（实现 Tracer 接口）
 //
 // 自动生成 trace_id（如果 context 中没有），
 // 并创建符合 W3C Trace Context 标准的 span。
+// 返回的 context 已包含该 span，可直接通过 SpanFromContext 获取。
 func (t *OTELTracer) StartSpan(ctx context.Context, operationName string) (Span, context.Context) {
-	ctx, span := t.tracer.Start(ctx, operationName)
-	return &OTELSpan{span: span}, ctx
+	return t.start(ctx, operationName)
+}
+
+// StartSpanWithKind 启动一个指定类型的 span（实现 SpanKindTracer 接口）
+func (t *OTELTracer) StartSpanWithKind(ctx context.Context, operationName string, kind SpanKind) (Span, context.Context) {
+	return t.start(ctx, operationName, trace.WithSpanKind(toOTELSpanKind(kind)))
+}
+
+// StartSpanWithOptions 使用可选参数启动 span（实现 OptionsTracer 接口）
+//
+// 属性、Links、开始时间在创建 span 时一并传给 OTel SDK，
+// 因此采样器在做采样决策时可以看到初始属性。
+func (t *OTELTracer) StartSpanWithOptions(ctx context.Context, operationName string, opts ...StartSpanOption) (Span, context.Context) {
+	config := NewStartSpanConfig(opts...)
+
+	otelOpts := []trace.SpanStartOption{trace.WithSpanKind(toOTELSpanKind(config.Kind))}
+	if len(config.Attributes) > 0 {
+		otelOpts = append(otelOpts, trace.WithAttributes(toOTELAttributes(config.Attributes)...))
+	}
+	for _, link := range config.Links {
+		if sc := otelSpanContext(link); sc.IsValid() {
+			otelOpts = append(otelOpts, trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
+	}
+	if !config.StartTime.IsZero() {
+		otelOpts = append(otelOpts, trace.WithTimestamp(config.StartTime))
+	}
+	if config.NewRoot {
+		otelOpts = append(otelOpts, trace.WithNewRoot())
+	}
+
+	return t.start(ctx, operationName, otelOpts...)
+}
+
+// start 创建 OTel span，并将 span 存入返回的 context
+func (t *OTELTracer) start(ctx context.Context, operationName string, opts ...trace.SpanStartOption) (Span, context.Context) {
+	ctx, span := t.tracer.Start(ctx, operationName, opts...)
+	otelSpan := &OTELSpan{span: span}
+	return otelSpan, ContextWithSpan(ctx, otelSpan)
+}
+
+// toOTELSpanKind 将 zltrace 的 SpanKind 转换为 OTel 的 SpanKind
+func toOTELSpanKind(kind SpanKind) trace.SpanKind {
+	switch kind {
+	case SpanKindServer:
+		return trace.SpanKindServer
+	case SpanKindClient:
+		return trace.SpanKindClient
+	case SpanKindProducer:
+		return trace.SpanKindProducer
+	case SpanKindConsumer:
+		return trace.SpanKindConsumer
+	default:
+		return trace.SpanKindInternal
+	}
 }
 
 // Inject 将 trace 上下文注入到 carrier（实现 Tracer 接口）
@@ -57,7 +158,53 @@ func (t *OTELTracer) Extract(ctx context.Context, carrier Carrier) (context.Cont
 }
 
 // Close 关闭追踪器（实现 Tracer 接口）
+//
+// 等价于使用默认超时（5 秒）调用 Shutdown，可以重复调用。
 func (t *OTELTracer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+	defer cancel()
+	return t.Shutdown(ctx)
+}
+
+// Shutdown 导出所有缓冲的 span 并关闭 TracerProvider 和 Exporter
+//
+// ctx 的 deadline 决定最多等待多久；超时后未导出的 span 会被丢弃。
+// 只有第一次调用会真正执行关闭，之后的调用返回第一次的结果。
+//
+// 不支持失败后重试：SDK 的 TracerProvider 在第一次 Shutdown 时就已标记为关闭，
+// 再次调用会直接返回 nil 而不会导出任何 span，因此这里保留第一次的错误，不会误报成功。
+// 需要尽量导出缓冲的 span 时，应在 Shutdown 之前调用 ForceFlush 并给足超时。
+func (t *OTELTracer) Shutdown(ctx context.Context) error {
+	t.shutdownOnce.Do(func() {
+		if t.provider == nil {
+			return
+		}
+		if err := t.provider.Shutdown(ctx); err != nil {
+			t.shutdownErr = fmt.Errorf("关闭 TracerProvider 失败: %w", err)
+		}
+	})
+	return t.shutdownErr
+}
+
+// ForceFlush 立即导出所有已结束但仍在缓冲中的 span
+//
+// 除了刷新批处理器，如果 Exporter 自身也有缓冲（实现了 ForceFlush），会一并刷新。
+func (t *OTELTracer) ForceFlush(ctx context.Context) error {
+	if t.provider == nil {
+		return nil
+	}
+	if err := t.provider.ForceFlush(ctx); err != nil {
+		return fmt.Errorf("刷新 TracerProvider 失败: %w", err)
+	}
+	for _, exporter := range t.exporters {
+		if flusher, ok := exporter.(interface {
+			ForceFlush(ctx context.Context) error
+		}); ok {
+			if err := flusher.ForceFlush(ctx); err != nil {
+				return fmt.Errorf("刷新 Exporter 失败: %w", err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -70,22 +217,48 @@ type OTELSpan struct {
 	span trace.Span
 }
 
-// Context 返回 span 的 context（实现 Span 接口）
+// Context 返回携带该 span 的 context（实现 Span 接口）
 func (s *OTELSpan) Context() context.Context {
-	return context.Background()
+	return ContextWithSpan(context.Background(), s)
 }
 
 // SetTag 设置标签（实现 Span 接口）
 func (s *OTELSpan) SetTag(key string, value interface{}) {
-	s.span.SetAttributes(attribute.String(key, fmt.Sprintf("%v", value)))
+	s.span.SetAttributes(toOTELAttribute(key, value))
 }
 
-// SetError 设置错误信息（实现 Span 接口）
-func (s *OTELSpan) SetError(err error) {
-	if err != nil {
-		s.span.RecordError(err)
-		s.span.SetAttributes(attribute.String("error", err.Error()))
+// SetTags 批量设置标签（实现 Span 接口）
+func (s *OTELSpan) SetTags(attrs ...Attribute) {
+	s.span.SetAttributes(toOTELAttributes(attrs)...)
+}
+
+// SetError 记录错误（实现 Span 接口）
+//
+// 记录一个 OTel exception 事件（exception.type、exception.message，
+// 可选 exception.stacktrace），并将 span 状态设置为 Error。
+// 为兼容已有的查询条件，同时保留 error 属性。
+func (s *OTELSpan) SetError(err error, opts ...ErrorOption) {
+	if err == nil {
+		return
 	}
+	config := NewErrorConfig(opts...)
+	s.span.RecordError(err, trace.WithStackTrace(config.StackTrace))
+	s.span.SetStatus(codes.Error, err.Error())
+	s.span.SetAttributes(attribute.String("error", err.Error()))
+}
+
+// AddEvent 添加带时间戳的事件（实现 Span 接口）
+func (s *OTELSpan) AddEvent(name string, attrs ...Attribute) {
+	if len(attrs) == 0 {
+		s.span.AddEvent(name)
+		return
+	}
+	s.span.AddEvent(name, trace.WithAttributes(toOTELAttributes(attrs)...))
+}
+
+// SetStatus 设置 span 状态（实现 Span 接口）
+func (s *OTELSpan) SetStatus(code StatusCode, description string) {
+	s.span.SetStatus(toOTELStatusCode(code), description)
 }
 
 // Finish 结束 span（实现 Span 接口）
@@ -101,6 +274,137 @@ func (s *OTELSpan) TraceID() string {
 	return spanCtx.TraceID().String()
 }
 
+// SpanID 返回 span_id（实现 Span 接口）
+//
+// 返回 W3C Trace Context 格式的 span_id（16位十六进制字符串）
+func (s *OTELSpan) SpanID() string {
+	return s.span.SpanContext().SpanID().String()
+}
+
+// IsRecording 是否正在记录（实现 Span 接口）
+func (s *OTELSpan) IsRecording() bool {
+	return s.span.IsRecording()
+}
+
+// IsSampled 是否被采样（实现 Span 接口）
+func (s *OTELSpan) IsSampled() bool {
+	return s.span.SpanContext().IsSampled()
+}
+
+// SpanContext 返回可移植的 span 上下文（实现 Span 接口）
+func (s *OTELSpan) SpanContext() SpanContext {
+	return spanContextFromOTEL(s.span.SpanContext())
+}
+
+// toOTELStatusCode 将 zltrace 的 StatusCode 转换为 OTel 的状态码
+func toOTELStatusCode(code StatusCode) codes.Code {
+	switch code {
+	case StatusOK:
+		return codes.Ok
+	case StatusError:
+		return codes.Error
+	default:
+		return codes.Unset
+	}
+}
+
+// otelSpanContext 获取 Span 对应的 OTel SpanContext（非 OTel 实现返回无效值）
+func otelSpanContext(span Span) trace.SpanContext {
+	if otelSpan, ok := span.(*OTELSpan); ok && otelSpan != nil {
+		return otelSpan.span.SpanContext()
+	}
+	return trace.SpanContext{}
+}
+
+// toOTELAttribute 将标签转换为对应类型的 OTel 属性
+//
+// 类型映射规则：
+//   - bool → BOOL
+//   - 有符号/无符号整数 → INT64（超出 int64 范围的 uint64 转为 STRING）
+//   - float32/float64 → FLOAT64
+//   - string → STRING
+//   - []bool、[]int、[]int64、[]float64、[]string → 对应的 SLICE 类型
+//   - time.Duration → INT64（毫秒）
+//   - time.Time → STRING（RFC3339Nano）
+//   - error → STRING（err.Error()）
+//   - fmt.Stringer → STRING（String()）
+//   - attribute.Value → 原样使用
+//
+// error 和 fmt.Stringer 通过 fmt.Sprint 转换：值为 nil 指针（如 (*MyErr)(nil)）时输出 <nil>，
+// Error()/String() panic 时输出 fmt 的错误标记，不会让 SetTag panic。
+//   - 其他类型 → STRING（fmt.Sprintf("%v")）
+func toOTELAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case attribute.Value:
+		return attribute.KeyValue{Key: attribute.Key(key), Value: v}
+	case bool:
+		return attribute.Bool(key, v)
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int64(key, int64(v))
+	case int16:
+		return attribute.Int64(key, int64(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint:
+		return uintAttribute(key, uint64(v))
+	case uint8:
+		return attribute.Int64(key, int64(v))
+	case uint16:
+		return attribute.Int64(key, int64(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case uint64:
+		return uintAttribute(key, v)
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case []bool:
+		return attribute.BoolSlice(key, v)
+	case []int:
+		return attribute.IntSlice(key, v)
+	case []int64:
+		return attribute.Int64Slice(key, v)
+	case []float64:
+		return attribute.Float64Slice(key, v)
+	case []string:
+		return attribute.StringSlice(key, v)
+	case time.Duration:
+		return attribute.Int64(key, v.Milliseconds())
+	case time.Time:
+		return attribute.String(key, v.Format(time.RFC3339Nano))
+	case error:
+		return attribute.String(key, fmt.Sprint(v))
+	case fmt.Stringer:
+		return attribute.String(key, fmt.Sprint(v))
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", value))
+	}
+}
+
+// uintAttribute 转换无符号整数，超出 int64 范围时使用字符串避免溢出
+func uintAttribute(key string, v uint64) attribute.KeyValue {
+	if v > math.MaxInt64 {
+		return attribute.String(key, strconv.FormatUint(v, 10))
+	}
+	return attribute.Int64(key, int64(v))
+}
+
+// toOTELAttributes 批量转换属性
+func toOTELAttributes(attrs []Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		kvs = append(kvs, toOTELAttribute(attr.Key, attr.Value))
+	}
+	return kvs
+}
+
 // ============================================================================
 // OpenTelemetry 初始化函数
 // ============================================================================
@@ -111,7 +415,10 @@ func (s *OTELSpan) TraceID() string {
 // 根据 exporter.type 决定追踪数据发送到哪里：
 //   - otlp: 发送到追踪系统（SkyWalking、Jaeger 等）
 //   - stdout: 输出到日志（降级模式）
+//   - file: 写入本地文件
 //   - none: 不发送追踪数据
+//
+// 配置了 exporter.types 时同时启用多个 exporter，每个 exporter 使用独立的批处理器。
 func InitOpenTelemetryTracer() error {
 	// 1. 读取配置
 	config, err := LoadConfig()
@@ -132,25 +439,27 @@ func InitOpenTelemetryTracer() error {
 		return fmt.Errorf("创建 OpenTelemetry Resource 失败: %w", err)
 	}
 
-	// 3. 创建 Exporter（根据 type 决定）
-	exporter, err := createExporterByType(config)
+	// 3. 创建采样器（包装为可热更新的采样器）
+	baseSampler, err := createSampler(config.Sampler)
+	if err != nil {
+		zllog.Error(context.Background(), "trace.init", "创建采样器失败", err)
+		return fmt.Errorf("创建采样器失败: %w", err)
+	}
+	sampler := newReloadableSampler(baseSampler)
+
+	// 4. 创建 Exporter（根据 type/types 决定）
+	exporters, err := createExporters(config)
 	if err != nil {
 		zllog.Error(context.Background(), "trace.init", "创建 OpenTelemetry Exporter 失败", err)
 		return fmt.Errorf("创建 OpenTelemetry Exporter 失败: %w", err)
 	}
 
-	// 4. 创建采样器
-	sampler := createSampler(config.Sampler)
-
 	// 5. 创建 TracerProvider
 	var tpOpts []sdktrace.TracerProviderOption
-	if exporter != nil {
-		// 只有当 exporter 不是 none 时才添加 Batcher
+	for _, exporter := range exporters {
+		// 每个 exporter 一个 Batcher，慢的 exporter 不会阻塞其他 exporter（none 不添加）
 		tpOpts = append(tpOpts,
-			sdktrace.WithBatcher(exporter,
-				sdktrace.WithBatchTimeout(time.Duration(config.Batch.Timeout)*time.Second),
-				sdktrace.WithMaxQueueSize(config.Batch.MaxQueueSize),
-			),
+			sdktrace.WithBatcher(exporter, batchSpanProcessorOptions(config.Batch)...),
 		)
 	}
 	tpOpts = append(tpOpts,
@@ -163,29 +472,55 @@ func InitOpenTelemetryTracer() error {
 	// 6. 设置全局 TracerProvider
 	otel.SetTracerProvider(tp)
 
-	// 7. 创建包装器并注册
-	tracer := tp.Tracer(config.ServiceName)
-	otelTracer := &OTELTracer{
-		tracer:     tracer,
-		propagator: propagation.TraceContext{}, // W3C Trace Context
-	}
+	// 7. 创建包装器并注册（OTELTracer 持有 TracerProvider，负责关闭时刷新缓冲的 span）
+	otelTracer := newOTELTracer(tp, exporters, config.ServiceName)
+	otelTracer.sampler = sampler
 
 	RegisterTracer(otelTracer)
 
-	// 8. 注册到 zllog
+	// 8. 注册到 zllog（trace_id 由 provider 提供，span_id 由包装后的 Logger 追加）
+	// 这里包装的是当前的全局 Logger：之后再调用 zllog.InitLogger / zllog.SetLogger 会替换掉包装，
+	// 日志中不再有 span_id。应先初始化日志再初始化 tracer，否则需要再调用一次 InstallSpanIDLogger()
 	zllog.RegisterTraceIDProvider(&OTELProvider{tracer: otelTracer, name: "opentelemetry"})
+	InstallSpanIDLogger()
 
 	// 9. 设置全局传播器
 	SetGlobalPropagators()
 
 	zllog.Info(context.Background(), "trace", "OpenTelemetry Tracer 初始化成功",
 		zllog.String("service_name", config.ServiceName),
-		zllog.String("exporter_type", config.Exporter.Type),
+		zllog.String("exporter_type", strings.Join(config.Exporter.enabledTypes(), ",")),
 		zllog.String("endpoint", config.Exporter.OTLP.Endpoint))
 
 	return nil
 }
 
+// batchSpanProcessorOptions 将 BatchConfig 转换为 BatchSpanProcessor 的选项
+// 值为 0 的配置项不设置，使用 OTel SDK 的默认值
+func batchSpanProcessorOptions(config BatchConfig) []sdktrace.BatchSpanProcessorOption {
+	var opts []sdktrace.BatchSpanProcessorOption
+
+	if config.ScheduleDelayMs > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(time.Duration(config.ScheduleDelayMs)*time.Millisecond))
+	} else if config.Timeout > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(time.Duration(config.Timeout)*time.Second))
+	}
+	if config.ExportTimeout > 0 {
+		opts = append(opts, sdktrace.WithExportTimeout(time.Duration(config.ExportTimeout)*time.Second))
+	}
+	if config.MaxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(config.MaxQueueSize))
+	}
+	if config.BatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(config.BatchSize))
+	}
+	if config.BlockOnQueueFull {
+		opts = append(opts, sdktrace.WithBlocking())
+	}
+
+	return opts
+}
+
 // SetGlobalPropagators 设置全局传播器
 //
 // 配置 W3C Trace Context 作为默认的上下文传播器。
@@ -193,7 +528,7 @@ func SetGlobalPropagators() {
 	// 使用 W3C Trace Context 作为默认传播器
 	propagator := propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{}, // W3C Trace Context (traceparent)
-		propagation.Baggage{},       // W3C Baggage
+		propagation.Baggage{},      // W3C Baggage
 	)
 
 	otel.SetTextMapPropagator(propagator)
@@ -213,17 +548,16 @@ type OTELProvider struct {
 }
 
 // GetTraceID 从 context 中提取 trace_id（实现 zllog.TraceIDProvider 接口）
+//
+// 支持 zltrace 创建的 span、OTel SDK 直接创建的 span，
+// 以及从上游提取的远程 span 上下文。
 func (p *OTELProvider) GetTraceID(ctx context.Context) string {
 	span := SpanFromContext(ctx)
 	if span == nil {
 		return ""
 	}
 
-	if otelSpan, ok := span.(*OTELSpan); ok {
-		return otelSpan.TraceID()
-	}
-
-	return ""
+	return span.TraceID()
 }
 
 // Name 返回追踪系统名称（实现 zllog.TraceIDProvider 接口）
@@ -276,33 +610,116 @@ func createResource(serviceName string) (*resource.Resource, error) {
 	)
 }
 
-// createExporterByType 根据 exporter.type 创建对应的 Exporter
-func createExporterByType(config *TraceConfig) (sdktrace.SpanExporter, error) {
-	switch config.Exporter.Type {
+// createExporters 创建所有启用的 Exporter（none 不创建）
+//
+// 每个 Exporter 都包装为 reportingExporter，导出失败时按 exporter 类型分别输出日志。
+// 其中一个创建失败时，已创建的 Exporter 会被关闭。
+func createExporters(config *TraceConfig) ([]sdktrace.SpanExporter, error) {
+	var exporters []sdktrace.SpanExporter
+	for _, exporterType := range config.Exporter.enabledTypes() {
+		exporter, err := createExporterByType(config, exporterType)
+		if err != nil {
+			for _, created := range exporters {
+				_ = created.Shutdown(context.Background())
+			}
+			return nil, err
+		}
+		if exporter != nil {
+			exporters = append(exporters, newReportingExporter(exporterType, exporter))
+		}
+	}
+	return exporters, nil
+}
+
+// createExporterByType 根据 exporter 类型创建对应的 Exporter
+func createExporterByType(config *TraceConfig, exporterType string) (sdktrace.SpanExporter, error) {
+	switch exporterType {
 	case "otlp":
-		return createOTLPExporter(config)
+		exporter, err := createOTLPExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		if config.Exporter.Spool.Enabled {
+			return NewSpoolExporter(exporter, config.Exporter.Spool)
+		}
+		if config.Exporter.Failover.Enabled {
+			return createFailoverExporter(exporter, config.Exporter)
+		}
+		return exporter, nil
 	case "stdout":
-		return createStdoutExporter()
+		return createStdoutExporter(config.Exporter.Stdout)
+	case "file":
+		return NewFileExporter(config.Exporter.File)
 	case "none":
 		return nil, nil
 	default:
-		return nil, fmt.Errorf("不支持的 exporter 类型: %s", config.Exporter.Type)
+		return nil, fmt.Errorf("不支持的 exporter 类型: %s", exporterType)
 	}
 }
 
-// createOTLPExporter 创建 OTLP gRPC Exporter
+// createFailoverExporter 为 OTLP Exporter 包装自动降级（降级到 stdout 配置的 LoggingExporter）
+func createFailoverExporter(primary sdktrace.SpanExporter, config ExporterConfig) (sdktrace.SpanExporter, error) {
+	fallback, err := createStdoutExporter(config.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	return NewFailoverExporter(primary, fallback,
+		WithFailureThreshold(config.Failover.FailureThreshold),
+		WithProbeInterval(config.Failover.ProbeInterval),
+	), nil
+}
+
+// createOTLPExporter 根据 protocol 创建 OTLP Exporter（gRPC 或 HTTP/protobuf）
 func createOTLPExporter(config *TraceConfig) (sdktrace.SpanExporter, error) {
+	switch config.Exporter.OTLP.Protocol {
+	case "", OTLPProtocolGRPC:
+		return createOTLPGRPCExporter(config)
+	case OTLPProtocolHTTPProtobuf:
+		return createOTLPHTTPExporter(config)
+	default:
+		return nil, fmt.Errorf("不支持的 OTLP 协议: %s", config.Exporter.OTLP.Protocol)
+	}
+}
+
+// createOTLPGRPCExporter 创建 OTLP gRPC Exporter
+func createOTLPGRPCExporter(config *TraceConfig) (sdktrace.SpanExporter, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Exporter.OTLP.Timeout)*time.Second)
 	defer cancel()
 
 	var opts []otlptracegrpc.Option
 	opts = append(opts, otlptracegrpc.WithEndpoint(config.Exporter.OTLP.Endpoint))
 
+	if config.Exporter.OTLP.Timeout > 0 {
+		opts = append(opts, otlptracegrpc.WithTimeout(time.Duration(config.Exporter.OTLP.Timeout)*time.Second))
+	}
+
 	if config.Exporter.OTLP.Insecure {
 		opts = append(opts, otlptracegrpc.WithInsecure())
-	} else {
-		// TODO: 支持 TLS
+	} else if config.Exporter.OTLP.TLS.isSet() {
+		tlsConfig, err := newTLSConfig(config.Exporter.OTLP.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("创建 OTLP TLS 配置失败: %w", err)
+		}
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	// 既不是 insecure 也没有 TLS 配置时，使用系统根证书校验服务端
+
+	headers, err := resolveHeaders(config.Exporter.OTLP.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("解析 OTLP 请求头失败: %w", err)
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(headers))
+	}
+	if config.Exporter.OTLP.Compression == "gzip" {
+		opts = append(opts, otlptracegrpc.WithCompressor("gzip"))
 	}
+	opts = append(opts, otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{
+		Enabled:         config.Exporter.Retry.Enabled,
+		InitialInterval: config.Exporter.Retry.InitialInterval,
+		MaxInterval:     config.Exporter.Retry.MaxInterval,
+		MaxElapsedTime:  config.Exporter.Retry.MaxElapsedTime,
+	}))
 
 	exporter, err := otlptracegrpc.New(ctx, opts...)
 	if err != nil {
@@ -312,27 +729,138 @@ func createOTLPExporter(config *TraceConfig) (sdktrace.SpanExporter, error) {
 	return exporter, nil
 }
 
+// createOTLPHTTPExporter 创建 OTLP HTTP/protobuf Exporter
+// 适用于只开放 HTTP 的网关，或只接受 OTLP/HTTP（4318 端口）的后端
+func createOTLPHTTPExporter(config *TraceConfig) (sdktrace.SpanExporter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Exporter.OTLP.Timeout)*time.Second)
+	defer cancel()
+
+	var opts []otlptracehttp.Option
+	opts = append(opts, otlptracehttp.WithEndpoint(config.Exporter.OTLP.Endpoint))
+
+	if config.Exporter.OTLP.Timeout > 0 {
+		opts = append(opts, otlptracehttp.WithTimeout(time.Duration(config.Exporter.OTLP.Timeout)*time.Second))
+	}
+	if config.Exporter.OTLP.URLPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(config.Exporter.OTLP.URLPath))
+	}
+
+	if config.Exporter.OTLP.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	} else if config.Exporter.OTLP.TLS.isSet() {
+		tlsConfig, err := newTLSConfig(config.Exporter.OTLP.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("创建 OTLP TLS 配置失败: %w", err)
+		}
+		opts = append(opts, otlptracehttp.WithTLSClientConfig(tlsConfig))
+	}
+
+	headers, err := resolveHeaders(config.Exporter.OTLP.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("解析 OTLP 请求头失败: %w", err)
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(headers))
+	}
+	if config.Exporter.OTLP.Compression == "gzip" {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+	opts = append(opts, otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+		Enabled:         config.Exporter.Retry.Enabled,
+		InitialInterval: config.Exporter.Retry.InitialInterval,
+		MaxInterval:     config.Exporter.Retry.MaxInterval,
+		MaxElapsedTime:  config.Exporter.Retry.MaxElapsedTime,
+	}))
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("创建 OTLP HTTP Exporter 失败: %w", err)
+	}
+
+	return exporter, nil
+}
+
 // createSampler 创建采样器
-func createSampler(config SamplerConfig) sdktrace.Sampler {
+//
+// 配置在 validateConfig 中已校验，未知类型不会到达这里；空值按 always_on 处理。
+func createSampler(config SamplerConfig) (sdktrace.Sampler, error) {
+	switch config.Type {
+	case "never":
+		return sdktrace.NeverSample(), nil
+	case "traceid_ratio":
+		return sdktrace.TraceIDRatioBased(config.Ratio), nil
+	case "parent_based":
+		return createParentBasedSampler(config, createRootSampler(config.Root)), nil
+	case "rate_limiting":
+		// 限流只作用于新 trace，有父 span 时跟随父 span 的决定，避免 trace 被截断
+		return createParentBasedSampler(config, newRateLimitingSampler(config.TracesPerSecond)), nil
+	case "rule_based":
+		// 与 rate_limiting 相同，规则只作用于新 trace
+		root, err := newRuleBasedSampler(config.Rules, config.Default)
+		if err != nil {
+			return nil, err
+		}
+		return createParentBasedSampler(config, root), nil
+	default:
+		return sdktrace.AlwaysSample(), nil
+	}
+}
+
+// createRootSampler 创建 parent_based 的根采样器
+func createRootSampler(config RootSamplerConfig) sdktrace.Sampler {
 	switch config.Type {
-	case "always_on":
-		return sdktrace.AlwaysSample()
 	case "never":
 		return sdktrace.NeverSample()
 	case "traceid_ratio":
 		return sdktrace.TraceIDRatioBased(config.Ratio)
-	case "parent_based":
-		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	case "rate_limiting":
+		return newRateLimitingSampler(config.TracesPerSecond)
 	default:
 		return sdktrace.AlwaysSample()
 	}
 }
 
+// createParentBasedSampler 创建基于父 span 的采样器
+//
+// 新 trace 使用 root 采样器；有父 span 时默认跟随父 span 的采样决定，
+// 上游（remote parent）的处理方式可以通过 remote_parent_sampled / remote_parent_not_sampled 调整。
+func createParentBasedSampler(config SamplerConfig, root sdktrace.Sampler) sdktrace.Sampler {
+	var opts []sdktrace.ParentBasedSamplerOption
+	if sampler := remoteParentSampler(config.RemoteParentSampled, root); sampler != nil {
+		opts = append(opts, sdktrace.WithRemoteParentSampled(sampler))
+	}
+	if sampler := remoteParentSampler(config.RemoteParentNotSampled, root); sampler != nil {
+		opts = append(opts, sdktrace.WithRemoteParentNotSampled(sampler))
+	}
+	return sdktrace.ParentBased(root, opts...)
+}
+
+// remoteParentSampler 将 remote_parent_* 配置转换为采样器，空值返回 nil（使用 OTel 默认行为）
+func remoteParentSampler(behavior string, root sdktrace.Sampler) sdktrace.Sampler {
+	switch behavior {
+	case "always_on":
+		return sdktrace.AlwaysSample()
+	case "never":
+		return sdktrace.NeverSample()
+	case "root":
+		return root
+	default:
+		return nil
+	}
+}
+
 // createStdoutExporter 创建 Stdout Exporter（降级模式）
 // 将追踪数据输出到日志，而不是发送到追踪系统
-func createStdoutExporter() (sdktrace.SpanExporter, error) {
+func createStdoutExporter(config StdoutConfig) (sdktrace.SpanExporter, error) {
 	// 使用 LoggingExporter 将 span 数据输出到日志
-	return &LoggingExporter{}, nil
+	opts := []LoggingExporterOption{WithLogLevel(config.Level)}
+	if config.OnlyErrors {
+		opts = append(opts, WithOnlyErrorSpans())
+	}
+	if config.SlowThresholdMs > 0 {
+		opts = append(opts, WithSlowSpanThreshold(time.Duration(config.SlowThresholdMs)*time.Millisecond))
+	}
+	return NewLoggingExporter(opts...), nil
 }
 
 // ============================================================================
@@ -341,29 +869,135 @@ func createStdoutExporter() (sdktrace.SpanExporter, error) {
 
 // LoggingExporter 将 span 数据输出到日志
 // 用于降级模式或调试场景
-type LoggingExporter struct{}
+//
+// 每个 span 输出一条日志，包含 trace_id、span_id、parent_span_id、kind、status、
+// 耗时、全部属性、事件和 resource。
+// 默认输出所有 span；配置了过滤条件时，满足任意一个条件的 span 才会输出。
+type LoggingExporter struct {
+	level         string
+	onlyErrors    bool
+	slowThreshold time.Duration
+}
+
+// LoggingExporterOption LoggingExporter 的可选参数
+type LoggingExporterOption func(*LoggingExporter)
+
+// WithLogLevel 设置输出的日志级别：debug（默认）、info、warn、error
+func WithLogLevel(level string) LoggingExporterOption {
+	return func(e *LoggingExporter) {
+		e.level = level
+	}
+}
+
+// WithOnlyErrorSpans 只输出状态为 Error 的 span
+func WithOnlyErrorSpans() LoggingExporterOption {
+	return func(e *LoggingExporter) {
+		e.onlyErrors = true
+	}
+}
+
+// WithSlowSpanThreshold 只输出耗时不小于 threshold 的 span
+func WithSlowSpanThreshold(threshold time.Duration) LoggingExporterOption {
+	return func(e *LoggingExporter) {
+		e.slowThreshold = threshold
+	}
+}
 
 // NewLoggingExporter 创建日志 Exporter
-func NewLoggingExporter() *LoggingExporter {
-	return &LoggingExporter{}
+func NewLoggingExporter(opts ...LoggingExporterOption) *LoggingExporter {
+	e := &LoggingExporter{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 // ExportSpans 导出 span 到日志
 func (e *LoggingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
 	for _, span := range spans {
-		spanCtx := span.SpanContext()
-		traceID := spanCtx.TraceID().String()
-		spanID := spanCtx.SpanID().String()
-
-		zllog.Debug(ctx, "otel_exporter", "OpenTelemetry Span",
-			zllog.String("trace_id", traceID),
-			zllog.String("span_id", spanID),
-			zllog.String("name", span.Name()),
-			zllog.Int("duration", int(span.EndTime().Sub(span.StartTime()).Milliseconds())))
+		if !e.shouldLog(span) {
+			continue
+		}
+		e.log(ctx, "OpenTelemetry Span", spanLogFields(span))
 	}
 	return nil
 }
 
+// shouldLog 判断 span 是否满足过滤条件
+func (e *LoggingExporter) shouldLog(span sdktrace.ReadOnlySpan) bool {
+	if !e.onlyErrors && e.slowThreshold <= 0 {
+		return true
+	}
+	if e.onlyErrors && span.Status().Code == codes.Error {
+		return true
+	}
+	return e.slowThreshold > 0 && span.EndTime().Sub(span.StartTime()) >= e.slowThreshold
+}
+
+// log 按配置的级别输出日志
+func (e *LoggingExporter) log(ctx context.Context, message string, fields []zllog.Field) {
+	switch e.level {
+	case "info":
+		zllog.Info(ctx, "otel_exporter", message, fields...)
+	case "warn":
+		zllog.Warn(ctx, "otel_exporter", message, fields...)
+	case "error":
+		zllog.Error(ctx, "otel_exporter", message, nil, fields...)
+	default:
+		zllog.Debug(ctx, "otel_exporter", message, fields...)
+	}
+}
+
+// spanLogFields 将 span 转换为日志字段
+func spanLogFields(span sdktrace.ReadOnlySpan) []zllog.Field {
+	spanCtx := span.SpanContext()
+	fields := []zllog.Field{
+		zllog.String("trace_id", spanCtx.TraceID().String()),
+		zllog.String("span_id", spanCtx.SpanID().String()),
+	}
+	if parent := span.Parent(); parent.IsValid() {
+		fields = append(fields, zllog.String("parent_span_id", parent.SpanID().String()))
+	}
+	fields = append(fields,
+		zllog.String("name", span.Name()),
+		zllog.String("kind", span.SpanKind().String()),
+		zllog.String("status", span.Status().Code.String()),
+		zllog.Int("duration", int(span.EndTime().Sub(span.StartTime()).Milliseconds())))
+	if desc := span.Status().Description; desc != "" {
+		fields = append(fields, zllog.String("status_description", desc))
+	}
+	if attrs := span.Attributes(); len(attrs) > 0 {
+		fields = append(fields, zllog.Any("attributes", attributeMap(attrs)))
+	}
+	if events := span.Events(); len(events) > 0 {
+		list := make([]map[string]interface{}, 0, len(events))
+		for _, event := range events {
+			item := map[string]interface{}{
+				"name": event.Name,
+				"time": event.Time.Format(time.RFC3339Nano),
+			}
+			if len(event.Attributes) > 0 {
+				item["attributes"] = attributeMap(event.Attributes)
+			}
+			list = append(list, item)
+		}
+		fields = append(fields, zllog.Any("events", list))
+	}
+	if res := span.Resource(); res != nil && res.Len() > 0 {
+		fields = append(fields, zllog.Any("resource", attributeMap(res.Attributes())))
+	}
+	return fields
+}
+
+// attributeMap 将 OTel 属性转换为 map，便于以 JSON 输出
+func attributeMap(attrs []attribute.KeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(attrs))
+	for _, kv := range attrs {
+		m[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return m
+}
+
 // Shutdown 关闭 Exporter
 func (e *LoggingExporter) Shutdown(ctx context.Context) error {
 	return nil
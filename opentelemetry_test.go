@@ -0,0 +1,1581 @@
+package zltrace
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zlxdbj/zllog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// initTestOTELTracer 通过 InitOpenTelemetryTracer 初始化全局 tracer（默认 stdout 配置）
+//
+// 测试结束时关闭 tracer，并恢复原来的 Tracer、otel 全局 TracerProvider 和 propagator。
+func initTestOTELTracer(t *testing.T) Tracer {
+	t.Helper()
+
+	logger := zllog.GetLogger()
+	previous := GetTracer()
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	if err := InitOpenTelemetryTracer(); err != nil {
+		t.Fatalf("InitOpenTelemetryTracer() error = %v", err)
+	}
+	tracer := GetTracer()
+	t.Cleanup(func() {
+		if tracer != nil {
+			tracer.Close()
+		}
+		RegisterTracer(previous)
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+		zllog.RegisterTraceIDProvider(nil)
+		zllog.SetLogger(logger)
+	})
+
+	if tracer == nil {
+		t.Fatal("global tracer should be registered")
+	}
+	return tracer
+}
+
+func TestOTELTracerStartSpanStoresSpanInContext(t *testing.T) {
+	tracer := initTestOTELTracer(t)
+
+	span, ctx := tracer.StartSpan(context.Background(), "test")
+	defer span.Finish()
+
+	got := SpanFromContext(ctx)
+	if got == nil {
+		t.Fatal("SpanFromContext should return the started span")
+	}
+	if got.TraceID() != span.TraceID() {
+		t.Errorf("trace_id mismatch: got %s, want %s", got.TraceID(), span.TraceID())
+	}
+
+	// 子 span 继承同一个 trace_id
+	child, childCtx := tracer.StartSpan(ctx, "child")
+	defer child.Finish()
+	if SpanFromContext(childCtx).TraceID() != span.TraceID() {
+		t.Error("child span should share the parent trace_id")
+	}
+}
+
+func TestLogCorrelationThroughZllog(t *testing.T) {
+	tracer := initTestOTELTracer(t)
+
+	span, ctx := tracer.StartSpan(context.Background(), "test")
+	defer span.Finish()
+
+	if got := zllog.GetOrCreateTraceID(ctx); got != span.TraceID() {
+		t.Errorf("zllog trace_id = %s, want %s", got, span.TraceID())
+	}
+
+	provider := zllog.GetTraceIDProvider()
+	if provider == nil {
+		t.Fatal("trace id provider should be registered to zllog")
+	}
+	if got := provider.GetTraceID(context.Background()); got != "" {
+		t.Errorf("expected empty trace_id without span, got %s", got)
+	}
+}
+
+func TestSpanFromContextThirdPartyOTELSpan(t *testing.T) {
+	tracer := initTestOTELTracer(t)
+
+	span, ctx := tracer.StartSpan(context.Background(), "zltrace")
+	defer span.Finish()
+
+	// 第三方 OTel 插桩通过全局 TracerProvider 创建子 span
+	ctx, thirdParty := otel.Tracer("third-party").Start(ctx, "third-party")
+	defer thirdParty.End()
+
+	got := SpanFromContext(ctx)
+	if got == nil {
+		t.Fatal("SpanFromContext should resolve third-party OTel spans")
+	}
+	if got.TraceID() != span.TraceID() {
+		t.Errorf("trace_id mismatch: got %s, want %s", got.TraceID(), span.TraceID())
+	}
+	if zllog.GetOrCreateTraceID(ctx) != span.TraceID() {
+		t.Error("zllog should correlate logs with third-party spans")
+	}
+}
+
+func TestSpanFromContextSDKSpan(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	ctx, sdkSpan := tp.Tracer("sdk").Start(context.Background(), "sdk")
+	defer sdkSpan.End()
+
+	got := SpanFromContext(ctx)
+	if got == nil {
+		t.Fatal("SpanFromContext should resolve spans created by the OTel SDK")
+	}
+	if got.TraceID() != sdkSpan.SpanContext().TraceID().String() {
+		t.Errorf("trace_id mismatch: got %s", got.TraceID())
+	}
+}
+
+func TestSpanFromContextWithoutSpan(t *testing.T) {
+	if span := SpanFromContext(context.Background()); span != nil {
+		t.Errorf("expected nil span, got %v", span)
+	}
+}
+
+// newBufferedTestTracer 创建一个批处理间隔很长的 OTELTracer，span 只有在刷新/关闭时才会导出
+func newBufferedTestTracer() (*OTELTracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(time.Hour)),
+	)
+	return newOTELTracer(tp, []sdktrace.SpanExporter{exporter}, "test"), exporter
+}
+
+// countingExporter 记录导出的 span 数量（InMemoryExporter 在 Shutdown 时会清空，不便验证关闭前的导出）
+type countingExporter struct {
+	mu       sync.Mutex
+	exported int
+	shutdown int
+}
+
+func (e *countingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.exported += len(spans)
+	return nil
+}
+
+func (e *countingExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown++
+	return nil
+}
+
+func TestOTELTracerCloseFlushesBufferedSpans(t *testing.T) {
+	exporter := &countingExporter{}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(time.Hour)),
+	)
+	tracer := newOTELTracer(tp, []sdktrace.SpanExporter{exporter}, "test")
+
+	span, _ := tracer.StartSpan(context.Background(), "buffered")
+	span.Finish()
+
+	if err := tracer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if exporter.exported != 1 {
+		t.Errorf("expected 1 span exported on Close, got %d", exporter.exported)
+	}
+	if exporter.shutdown != 1 {
+		t.Errorf("expected exporter to be shut down once, got %d", exporter.shutdown)
+	}
+
+	// Close 可以重复调用
+	if err := tracer.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() after Close() error = %v", err)
+	}
+}
+
+func TestOTELTracerForceFlush(t *testing.T) {
+	tracer, exporter := newBufferedTestTracer()
+	defer tracer.Close()
+
+	span, _ := tracer.StartSpan(context.Background(), "buffered")
+	span.Finish()
+
+	if err := tracer.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "buffered" {
+		t.Errorf("expected buffered span to be exported, got %v", spans)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tracer.ForceFlush(ctx); err == nil {
+		t.Error("ForceFlush() with canceled context should return error")
+	}
+}
+
+func TestShutdownGlobalTracer(t *testing.T) {
+	tracer, _ := newBufferedTestTracer()
+	RegisterTracer(tracer)
+	defer RegisterTracer(nil)
+
+	if err := ForceFlush(context.Background()); err != nil {
+		t.Errorf("ForceFlush() error = %v", err)
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+
+	// 没有注册 tracer 时不报错
+	RegisterTracer(nil)
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() without tracer error = %v", err)
+	}
+
+	stop := ShutdownOnSignal(time.Second)
+	stop()
+	stop()
+}
+
+func TestOTELTracerShutdownErrorIsPermanent(t *testing.T) {
+	tracer, _ := newBufferedTestTracer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := tracer.Shutdown(ctx)
+	if err == nil {
+		t.Fatal("Shutdown() with canceled context should return error")
+	}
+
+	// SDK 已经标记为关闭，重试不会再导出，不能误报成功
+	if retry := tracer.Shutdown(context.Background()); retry != err {
+		t.Errorf("retried Shutdown() error = %v, want %v", retry, err)
+	}
+}
+
+// newSyncTestTracer 创建同步导出的 OTELTracer，span 结束后立即可以在 exporter 中看到
+func newSyncTestTracer() (*OTELTracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return newOTELTracer(tp, []sdktrace.SpanExporter{exporter}, "test"), exporter
+}
+
+func TestOTELTracerStartSpanWithKind(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+
+	tests := []struct {
+		kind SpanKind
+		want trace.SpanKind
+	}{
+		{SpanKindInternal, trace.SpanKindInternal},
+		{SpanKindServer, trace.SpanKindServer},
+		{SpanKindClient, trace.SpanKindClient},
+		{SpanKindProducer, trace.SpanKindProducer},
+		{SpanKindConsumer, trace.SpanKindConsumer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			exporter.Reset()
+			span, _ := StartSpanWithKind(tracer, context.Background(), "op", tt.kind)
+			span.Finish()
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			if spans[0].SpanKind != tt.want {
+				t.Errorf("span kind = %v, want %v", spans[0].SpanKind, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceHTTPRequestCreatesServerSpan(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+	RegisterTracer(tracer)
+	defer RegisterTracer(nil)
+
+	handler := &testHTTPHandler{method: "GET", url: "/users", headers: map[string]string{}}
+	TraceHTTPRequest(context.Background(), handler, func() {})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "GET /users" {
+		t.Errorf("span name = %s", spans[0].Name)
+	}
+	if spans[0].SpanKind != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", spans[0].SpanKind)
+	}
+}
+
+// testHTTPHandler 实现 HTTPTraceHandler 接口，用于测试
+type testHTTPHandler struct {
+	method  string
+	url     string
+	headers map[string]string
+	ctx     context.Context
+}
+
+func (h *testHTTPHandler) GetMethod() string                  { return h.method }
+func (h *testHTTPHandler) GetURL() string                     { return h.url }
+func (h *testHTTPHandler) GetHeader(key string) string        { return h.headers[key] }
+func (h *testHTTPHandler) SetSpanContext(ctx context.Context) { h.ctx = ctx }
+func (h *testHTTPHandler) GetSpanContext() context.Context    { return h.ctx }
+
+func TestOTELTracerStartSpanWithOptions(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+
+	linked, _ := tracer.StartSpan(context.Background(), "linked")
+	linked.Finish()
+	parent, parentCtx := tracer.StartSpan(context.Background(), "parent")
+	defer parent.Finish()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	span, _ := StartSpanWithOptions(tracer, parentCtx, "with-options",
+		WithSpanKind(SpanKindClient),
+		WithAttributes(Attr("http.method", "GET")),
+		WithLinks(linked),
+		WithStartTime(start),
+	)
+	span.Finish()
+
+	root, _ := StartSpanWithOptions(tracer, parentCtx, "new-root", WithNewRoot())
+	root.Finish()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	got := spans[1]
+	if got.SpanKind != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", got.SpanKind)
+	}
+	if len(got.Attributes) != 1 || got.Attributes[0].Key != "http.method" {
+		t.Errorf("unexpected attributes: %v", got.Attributes)
+	}
+	if len(got.Links) != 1 || got.Links[0].SpanContext.TraceID().String() != linked.TraceID() {
+		t.Errorf("unexpected links: %v", got.Links)
+	}
+	if !got.StartTime.Equal(start) {
+		t.Errorf("start time = %v, want %v", got.StartTime, start)
+	}
+	if got.Parent.TraceID().String() != parent.TraceID() {
+		t.Error("span should be a child of parent")
+	}
+
+	if spans[2].Parent.IsValid() || spans[2].SpanContext.TraceID().String() == parent.TraceID() {
+		t.Error("WithNewRoot should start a new trace")
+	}
+}
+
+func TestOTELTracerAttributesVisibleToSampler(t *testing.T) {
+	sampler := &recordingSampler{}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler))
+	tracer := newOTELTracer(tp, nil, "test")
+	defer tracer.Close()
+
+	span, _ := StartSpanWithOptions(tracer, context.Background(), "op",
+		WithAttributes(Attr("http.route", "/health")))
+	span.Finish()
+
+	if len(sampler.attributes) != 1 || sampler.attributes[0].Value.AsString() != "/health" {
+		t.Errorf("sampler should see initial attributes, got %v", sampler.attributes)
+	}
+}
+
+// recordingSampler 记录采样时看到的初始属性
+type recordingSampler struct {
+	attributes []attribute.KeyValue
+}
+
+func (s *recordingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s.attributes = p.Attributes
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample}
+}
+
+func (s *recordingSampler) Description() string {
+	return "recordingSampler"
+}
+
+func TestCreateSamplerParentBased(t *testing.T) {
+	// TraceIDRatioBased 按 trace_id 低 8 字节判断：全 0 一定采样，全 f 一定不采样
+	lowID := trace.TraceID{1}
+	highID := trace.TraceID{1, 8: 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	remote := func(sampled bool) trace.SpanContext {
+		config := trace.SpanContextConfig{TraceID: lowID, SpanID: trace.SpanID{1}, Remote: true}
+		if sampled {
+			config.TraceFlags = trace.FlagsSampled
+		}
+		return trace.NewSpanContext(config)
+	}
+	decide := func(sampler sdktrace.Sampler, parent trace.SpanContext, traceID trace.TraceID) bool {
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+		result := sampler.ShouldSample(sdktrace.SamplingParameters{ParentContext: ctx, TraceID: traceID, Name: "op"})
+		return result.Decision == sdktrace.RecordAndSample
+	}
+	ratio := RootSamplerConfig{Type: "traceid_ratio", Ratio: 0.1}
+
+	tests := []struct {
+		name    string
+		config  SamplerConfig
+		parent  trace.SpanContext
+		traceID trace.TraceID
+		want    bool
+	}{
+		{"default root samples new traces", SamplerConfig{Type: "parent_based"}, trace.SpanContext{}, highID, true},
+		{"ratio root samples low trace id", SamplerConfig{Type: "parent_based", Root: ratio}, trace.SpanContext{}, lowID, true},
+		{"ratio root drops high trace id", SamplerConfig{Type: "parent_based", Root: ratio}, trace.SpanContext{}, highID, false},
+		{"follows sampled upstream", SamplerConfig{Type: "parent_based", Root: RootSamplerConfig{Type: "never"}}, remote(true), lowID, true},
+		{"follows not sampled upstream", SamplerConfig{Type: "parent_based"}, remote(false), lowID, false},
+		{"remote sampled never", SamplerConfig{Type: "parent_based", RemoteParentSampled: "never"}, remote(true), lowID, false},
+		{"remote not sampled always_on", SamplerConfig{Type: "parent_based", RemoteParentNotSampled: "always_on"}, remote(false), lowID, true},
+		{"remote not sampled uses root", SamplerConfig{Type: "parent_based", Root: ratio, RemoteParentNotSampled: "root"}, remote(false), lowID, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler, err := createSampler(tt.config)
+			if err != nil {
+				t.Fatalf("createSampler() error = %v", err)
+			}
+			if got := decide(sampler, tt.parent, tt.traceID); got != tt.want {
+				t.Errorf("sampled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSamplerConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		sampler SamplerConfig
+		wantErr bool
+	}{
+		{"empty", SamplerConfig{}, false},
+		{"traceid_ratio", SamplerConfig{Type: "traceid_ratio", Ratio: 0.5}, false},
+		{"parent_based with ratio root", SamplerConfig{Type: "parent_based", Root: RootSamplerConfig{Type: "traceid_ratio", Ratio: 0.1}, RemoteParentNotSampled: "root"}, false},
+		{"unknown type", SamplerConfig{Type: "probabilistic"}, true},
+		{"ratio out of range", SamplerConfig{Type: "traceid_ratio", Ratio: 1.5}, true},
+		{"unknown root type", SamplerConfig{Type: "parent_based", Root: RootSamplerConfig{Type: "parent_based"}}, true},
+		{"root ratio out of range", SamplerConfig{Type: "parent_based", Root: RootSamplerConfig{Type: "traceid_ratio", Ratio: -0.1}}, true},
+		{"unknown remote behavior", SamplerConfig{Type: "parent_based", RemoteParentSampled: "sometimes"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &TraceConfig{Enabled: true, Sampler: tt.sampler, Exporter: ExporterConfig{Type: "stdout"}}
+			err := validateConfig(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testStringer 用于测试 fmt.Stringer 的映射
+type testStringer struct{}
+
+func (testStringer) String() string { return "stringer" }
+
+// testPtrError 指针接收者的 error，用于测试 nil 指针
+type testPtrError struct{ msg string }
+
+func (e *testPtrError) Error() string { return e.msg }
+
+// testPtrStringer 指针接收者的 fmt.Stringer，用于测试 nil 指针
+type testPtrStringer struct{ s string }
+
+func (s *testPtrStringer) String() string { return s.s }
+
+func TestToOTELAttribute(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		wantType attribute.Type
+		want     interface{}
+	}{
+		{"bool", true, attribute.BOOL, true},
+		{"string", "hello", attribute.STRING, "hello"},
+		{"int", 200, attribute.INT64, int64(200)},
+		{"int8", int8(-8), attribute.INT64, int64(-8)},
+		{"int16", int16(16), attribute.INT64, int64(16)},
+		{"int32", int32(3), attribute.INT64, int64(3)},
+		{"int64", int64(123456789), attribute.INT64, int64(123456789)},
+		{"uint", uint(7), attribute.INT64, int64(7)},
+		{"uint8", uint8(8), attribute.INT64, int64(8)},
+		{"uint16", uint16(16), attribute.INT64, int64(16)},
+		{"uint32", uint32(32), attribute.INT64, int64(32)},
+		{"uint64", uint64(64), attribute.INT64, int64(64)},
+		{"uint64 overflow", uint64(math.MaxUint64), attribute.STRING, "18446744073709551615"},
+		{"float32", float32(1.5), attribute.FLOAT64, 1.5},
+		{"float64", 2.25, attribute.FLOAT64, 2.25},
+		{"[]bool", []bool{true, false}, attribute.BOOLSLICE, []bool{true, false}},
+		{"[]int", []int{1, 2}, attribute.INT64SLICE, []int64{1, 2}},
+		{"[]int64", []int64{3, 4}, attribute.INT64SLICE, []int64{3, 4}},
+		{"[]float64", []float64{0.5}, attribute.FLOAT64SLICE, []float64{0.5}},
+		{"[]string", []string{"a", "b"}, attribute.STRINGSLICE, []string{"a", "b"}},
+		{"duration", 1500 * time.Millisecond, attribute.INT64, int64(1500)},
+		{"time", now, attribute.STRING, "2024-01-02T03:04:05Z"},
+		{"error", errors.New("boom"), attribute.STRING, "boom"},
+		{"stringer", testStringer{}, attribute.STRING, "stringer"},
+		{"nil pointer error", (*testPtrError)(nil), attribute.STRING, "<nil>"},
+		{"nil pointer stringer", (*testPtrStringer)(nil), attribute.STRING, "<nil>"},
+		{"attribute.Value", attribute.IntValue(9), attribute.INT64, int64(9)},
+		{"fallback", struct{ A int }{1}, attribute.STRING, "{1}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv := toOTELAttribute("key", tt.value)
+			if kv.Key != "key" {
+				t.Errorf("key = %s, want key", kv.Key)
+			}
+			if kv.Value.Type() != tt.wantType {
+				t.Fatalf("type = %v, want %v", kv.Value.Type(), tt.wantType)
+			}
+			if got := kv.Value.AsInterface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("value = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOTELSpanSetTags(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+
+	span, _ := tracer.StartSpan(context.Background(), "tags")
+	span.SetTag("http.status_code", 503)
+	span.SetTags(Attr("kafka.partition", int32(2)), Attr("kafka.offset", int64(42)))
+	span.Finish()
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range exporter.GetSpans()[0].Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs["http.status_code"]; v.Type() != attribute.INT64 || v.AsInt64() != 503 {
+		t.Errorf("http.status_code = %v", v.Emit())
+	}
+	if v := attrs["kafka.partition"]; v.Type() != attribute.INT64 || v.AsInt64() != 2 {
+		t.Errorf("kafka.partition = %v", v.Emit())
+	}
+	if v := attrs["kafka.offset"]; v.Type() != attribute.INT64 || v.AsInt64() != 42 {
+		t.Errorf("kafka.offset = %v", v.Emit())
+	}
+}
+
+func TestOTELSpanAddEvent(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+
+	span, _ := tracer.StartSpan(context.Background(), "events")
+	span.AddEvent("cache.miss", Attr("cache.key", "user:1"))
+	span.AddEvent("retry", Attr("retry.attempt", 2))
+	span.AddEvent("checkpoint")
+	span.Finish()
+
+	events := exporter.GetSpans()[0].Events
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[0].Name != "cache.miss" || events[0].Attributes[0].Value.AsString() != "user:1" {
+		t.Errorf("unexpected event: %+v", events[0])
+	}
+	if events[1].Attributes[0].Value.AsInt64() != 2 {
+		t.Errorf("retry.attempt should be an int, got %v", events[1].Attributes[0].Value.Emit())
+	}
+	if events[2].Time.IsZero() {
+		t.Error("event should have a timestamp")
+	}
+}
+
+func TestOTELSpanSetError(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+
+	span, _ := tracer.StartSpan(context.Background(), "error")
+	span.SetError(nil)
+	span.SetError(errors.New("boom"))
+	span.Finish()
+
+	stackSpan, _ := tracer.StartSpan(context.Background(), "error-with-stack")
+	stackSpan.SetError(errors.New("boom"), WithStackTrace())
+	stackSpan.Finish()
+
+	spans := exporter.GetSpans()
+	got := spans[0]
+	if got.Status.Code != codes.Error || got.Status.Description != "boom" {
+		t.Errorf("status = %+v, want Error/boom", got.Status)
+	}
+	if len(got.Events) != 1 || got.Events[0].Name != "exception" {
+		t.Fatalf("expected one exception event, got %+v", got.Events)
+	}
+	attrs := map[attribute.Key]string{}
+	for _, kv := range got.Events[0].Attributes {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	if attrs["exception.type"] != "*errors.errorString" || attrs["exception.message"] != "boom" {
+		t.Errorf("unexpected exception attributes: %v", attrs)
+	}
+	if _, ok := attrs["exception.stacktrace"]; ok {
+		t.Error("stack trace should not be recorded by default")
+	}
+
+	stackAttrs := map[attribute.Key]string{}
+	for _, kv := range spans[1].Events[0].Attributes {
+		stackAttrs[kv.Key] = kv.Value.Emit()
+	}
+	if stackAttrs["exception.stacktrace"] == "" {
+		t.Error("WithStackTrace should record exception.stacktrace")
+	}
+}
+
+func TestOTELSpanSetStatus(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+
+	tests := []struct {
+		code StatusCode
+		want codes.Code
+	}{
+		{StatusUnset, codes.Unset},
+		{StatusOK, codes.Ok},
+		{StatusError, codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			exporter.Reset()
+			span, _ := tracer.StartSpan(context.Background(), "status")
+			span.SetStatus(tt.code, "description")
+			span.Finish()
+
+			if got := exporter.GetSpans()[0].Status.Code; got != tt.want {
+				t.Errorf("status = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinishSpan(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+
+	ok, _ := tracer.StartSpan(context.Background(), "ok")
+	FinishSpan(ok, nil)
+	failed, _ := tracer.StartSpan(context.Background(), "failed")
+	FinishSpan(failed, errors.New("send failed"))
+	FinishSpan(nil, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 finished spans, got %d", len(spans))
+	}
+	if spans[0].Status.Code != codes.Unset {
+		t.Errorf("successful span status = %v, want Unset", spans[0].Status.Code)
+	}
+	if spans[1].Status.Code != codes.Error {
+		t.Errorf("failed span status = %v, want Error", spans[1].Status.Code)
+	}
+}
+
+func TestTraceHTTPRequestStatusMapping(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+	RegisterTracer(tracer)
+	defer RegisterTracer(nil)
+
+	tests := []struct {
+		statusCode int
+		want       codes.Code
+	}{
+		{200, codes.Unset},
+		{404, codes.Unset},
+		{500, codes.Error},
+		{503, codes.Error},
+	}
+
+	for _, tt := range tests {
+		exporter.Reset()
+		handler := &testStatusHTTPHandler{
+			testHTTPHandler: testHTTPHandler{method: "GET", url: "/users", headers: map[string]string{}},
+			statusCode:      tt.statusCode,
+		}
+		TraceHTTPRequest(context.Background(), handler, func() {})
+
+		got := exporter.GetSpans()[0]
+		if got.Status.Code != tt.want {
+			t.Errorf("HTTP %d: status = %v, want %v", tt.statusCode, got.Status.Code, tt.want)
+		}
+		var statusAttr int64
+		for _, kv := range got.Attributes {
+			if kv.Key == "http.status_code" {
+				statusAttr = kv.Value.AsInt64()
+			}
+		}
+		if statusAttr != int64(tt.statusCode) {
+			t.Errorf("http.status_code = %d, want %d", statusAttr, tt.statusCode)
+		}
+	}
+}
+
+// testStatusHTTPHandler 额外实现 HTTPStatusHandler 接口
+type testStatusHTTPHandler struct {
+	testHTTPHandler
+	statusCode int
+}
+
+func (h *testStatusHTTPHandler) GetStatusCode() int { return h.statusCode }
+
+func TestOTELSpanSpanContext(t *testing.T) {
+	tracer, _ := newSyncTestTracer()
+	defer tracer.Close()
+
+	span, _ := tracer.StartSpan(context.Background(), "test")
+	if len(span.SpanID()) != 16 {
+		t.Errorf("SpanID() = %q, want 16 hex chars", span.SpanID())
+	}
+	if !span.IsRecording() || !span.IsSampled() {
+		t.Error("span should be recording and sampled with the default sampler")
+	}
+
+	sc := span.SpanContext()
+	if sc.TraceID != span.TraceID() || sc.SpanID != span.SpanID() || !sc.Sampled || sc.Remote {
+		t.Errorf("unexpected SpanContext: %+v", sc)
+	}
+
+	span.Finish()
+	if span.IsRecording() {
+		t.Error("finished span should not be recording")
+	}
+}
+
+func TestSpanContextStringRoundTrip(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := ParseSpanContext(traceparent)
+	if err != nil {
+		t.Fatalf("ParseSpanContext() error = %v", err)
+	}
+	want := SpanContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Sampled: true,
+		Remote:  true,
+	}
+	if sc != want {
+		t.Errorf("ParseSpanContext() = %+v, want %+v", sc, want)
+	}
+	if sc.String() != traceparent {
+		t.Errorf("String() = %s, want %s", sc.String(), traceparent)
+	}
+
+	notSampled, err := ParseSpanContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if err != nil || notSampled.Sampled {
+		t.Errorf("flags 00 should parse as not sampled, got %+v, err = %v", notSampled, err)
+	}
+	if (SpanContext{}).String() != "" {
+		t.Error("invalid SpanContext should format as empty string")
+	}
+}
+
+func TestParseSpanContextInvalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	}
+	for _, input := range inputs {
+		if _, err := ParseSpanContext(input); err == nil {
+			t.Errorf("ParseSpanContext(%q) should fail", input)
+		}
+	}
+}
+
+func TestContextWithRemoteSpanContext(t *testing.T) {
+	tracer, _ := newSyncTestTracer()
+	defer tracer.Close()
+
+	parent, _ := ParseSpanContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	span, _ := tracer.StartSpan(ContextWithRemoteSpanContext(context.Background(), parent), "child")
+	defer span.Finish()
+
+	if span.TraceID() != parent.TraceID {
+		t.Errorf("trace_id = %s, want %s", span.TraceID(), parent.TraceID)
+	}
+	if span.SpanID() == parent.SpanID {
+		t.Error("child span should have its own span_id")
+	}
+}
+
+func TestSpanContextInvalidTraceState(t *testing.T) {
+	tracer, _ := newSyncTestTracer()
+	defer tracer.Close()
+
+	// tracestate 无效时丢弃 tracestate，仍然继续上游的 trace
+	parent, _ := ParseSpanContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.TraceState = "invalid tracestate"
+	if !parent.IsValid() {
+		t.Fatal("IsValid() should only check trace_id and span_id")
+	}
+	if parent.String() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("String() = %q", parent.String())
+	}
+
+	ctx := ContextWithRemoteSpanContext(context.Background(), parent)
+	remote := trace.SpanContextFromContext(ctx)
+	if remote.TraceID().String() != parent.TraceID || remote.TraceState().Len() != 0 {
+		t.Errorf("remote span context = %s tracestate=%q, want trace_id %s without tracestate",
+			remote.TraceID(), remote.TraceState().String(), parent.TraceID)
+	}
+	span, _ := tracer.StartSpan(ctx, "child")
+	defer span.Finish()
+	if span.TraceID() != parent.TraceID {
+		t.Errorf("trace_id = %s, want %s", span.TraceID(), parent.TraceID)
+	}
+}
+
+// recordingLogger 记录最后一次日志的级别和字段
+type recordingLogger struct {
+	level  string
+	fields []zllog.Field
+	count  int
+}
+
+func (l *recordingLogger) record(level string, fields []zllog.Field) {
+	l.level, l.fields = level, fields
+	l.count++
+}
+
+func (l *recordingLogger) Debug(ctx context.Context, module, message string, fields ...zllog.Field) {
+	l.record("debug", fields)
+}
+func (l *recordingLogger) Info(ctx context.Context, module, message string, fields ...zllog.Field) {
+	l.record("info", fields)
+}
+func (l *recordingLogger) Warn(ctx context.Context, module, message string, fields ...zllog.Field) {
+	l.record("warn", fields)
+}
+func (l *recordingLogger) Error(ctx context.Context, module, message string, err error, fields ...zllog.Field) {
+	l.record("error", fields)
+}
+func (l *recordingLogger) ErrorWithCode(ctx context.Context, module, message, errorCode string, err error, fields ...zllog.Field) {
+	l.record("error", fields)
+}
+func (l *recordingLogger) Fatal(ctx context.Context, module, message string, err error, fields ...zllog.Field) {
+	l.record("fatal", fields)
+}
+func (l *recordingLogger) InfoWithRequest(ctx context.Context, module, message, requestID string, costMs int64, fields ...zllog.Field) {
+	l.record("info", fields)
+}
+func (l *recordingLogger) ErrorWithRequest(ctx context.Context, module, message, requestID string, err error, costMs int64, fields ...zllog.Field) {
+	l.record("error", fields)
+}
+
+func (l *recordingLogger) field(key string) (interface{}, bool) {
+	for _, f := range l.fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+func TestSpanIDLogger(t *testing.T) {
+	original := zllog.GetLogger()
+	defer zllog.SetLogger(original)
+
+	recorder := &recordingLogger{}
+	zllog.SetLogger(recorder)
+	InstallSpanIDLogger()
+	InstallSpanIDLogger() // 重复调用不应重复包装
+
+	tracer, _ := newSyncTestTracer()
+	defer tracer.Close()
+	span, ctx := tracer.StartSpan(context.Background(), "test")
+	defer span.Finish()
+
+	zllog.Info(ctx, "test", "with span", zllog.String("k", "v"))
+	if got, _ := recorder.field("span_id"); got != span.SpanID() {
+		t.Errorf("span_id = %v, want %s", got, span.SpanID())
+	}
+	if len(recorder.fields) != 2 {
+		t.Errorf("expected 2 fields, got %d", len(recorder.fields))
+	}
+
+	zllog.Info(context.Background(), "test", "without span")
+	if _, ok := recorder.field("span_id"); ok {
+		t.Error("span_id should not be logged without a span")
+	}
+}
+
+func TestOTELSpanContextRoundTrip(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+
+	span, _ := tracer.StartSpan(context.Background(), "parent")
+
+	got := SpanFromContext(span.Context())
+	if got == nil || got.SpanID() != span.SpanID() {
+		t.Fatal("span.Context() should carry the span")
+	}
+
+	// 交给其他 goroutine 后继续 trace
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		child, _ := tracer.StartSpan(span.Context(), "worker")
+		child.Finish()
+	}()
+	<-done
+	span.Finish()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent.SpanID().String() != span.SpanID() {
+		t.Error("worker span should be a child of the parent span")
+	}
+}
+
+func TestAttachSpanKeepsParentContext(t *testing.T) {
+	tracer, _ := newSyncTestTracer()
+	defer tracer.Close()
+
+	span, _ := tracer.StartSpan(context.Background(), "test")
+	defer span.Finish()
+
+	type key struct{}
+	deadline := time.Now().Add(time.Hour)
+	parent, cancel := context.WithDeadline(context.WithValue(context.Background(), key{}, "v"), deadline)
+	defer cancel()
+
+	ctx := AttachSpan(parent, span)
+	if got := SpanFromContext(ctx); got == nil || got.SpanID() != span.SpanID() {
+		t.Fatal("AttachSpan should make the span current")
+	}
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(deadline) {
+		t.Error("AttachSpan should keep the parent deadline")
+	}
+	if ctx.Value(key{}) != "v" {
+		t.Error("AttachSpan should keep the parent values")
+	}
+	if AttachSpan(parent, nil) != parent {
+		t.Error("AttachSpan with nil span should return the parent")
+	}
+}
+
+func TestDetach(t *testing.T) {
+	tracer, _ := newSyncTestTracer()
+	defer tracer.Close()
+
+	span, ctx := tracer.StartSpan(context.Background(), "request")
+	defer span.Finish()
+	ctx, cancel := context.WithTimeout(ctx, time.Hour)
+	cancel()
+
+	detached := Detach(ctx)
+	if detached.Err() != nil {
+		t.Error("detached context should not be canceled")
+	}
+	if _, ok := detached.Deadline(); ok {
+		t.Error("detached context should not have a deadline")
+	}
+	if got := SpanFromContext(detached); got == nil || got.SpanID() != span.SpanID() {
+		t.Error("detached context should keep the span")
+	}
+}
+
+func TestGo(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+	RegisterTracer(tracer)
+	defer RegisterTracer(nil)
+
+	parent, ctx := tracer.StartSpan(context.Background(), "request")
+	defer parent.Finish()
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	wantErr := errors.New("boom")
+	err := <-Go(ctx, "async.job", func(ctx context.Context) error {
+		if ctx.Err() != nil {
+			t.Error("async job context should not inherit cancellation")
+		}
+		return wantErr
+	})
+	if err != wantErr {
+		t.Errorf("Go() error = %v, want %v", err, wantErr)
+	}
+
+	got := exporter.GetSpans()[0]
+	if got.Name != "async.job" || got.Parent.SpanID().String() != parent.SpanID() {
+		t.Errorf("async span should be a child of the request span, got %s", got.Name)
+	}
+	if got.Status.Code != codes.Error {
+		t.Errorf("status = %v, want Error", got.Status.Code)
+	}
+}
+
+func TestGoRecoversPanic(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+	RegisterTracer(tracer)
+	defer RegisterTracer(nil)
+
+	err := <-Go(context.Background(), "async.panic", func(ctx context.Context) error {
+		panic("oops")
+	})
+	if err == nil {
+		t.Fatal("Go() should report the recovered panic")
+	}
+
+	got := exporter.GetSpans()[0]
+	if got.Status.Code != codes.Error || len(got.Events) == 0 || got.Events[0].Name != "exception" {
+		t.Fatal("panic should be recorded as an exception on the span")
+	}
+	for _, kv := range got.Events[0].Attributes {
+		if kv.Key == "exception.stacktrace" && kv.Value.AsString() != "" {
+			return
+		}
+	}
+	t.Error("panic should record the stack trace")
+}
+
+func TestGoWithLinkedParent(t *testing.T) {
+	tracer, exporter := newSyncTestTracer()
+	defer tracer.Close()
+	RegisterTracer(tracer)
+	defer RegisterTracer(nil)
+
+	parent, ctx := tracer.StartSpan(context.Background(), "request")
+	defer parent.Finish()
+
+	<-Go(ctx, "async.linked", func(ctx context.Context) error { return nil },
+		WithLinkedParent(SpanFromContext(ctx)))
+
+	got := exporter.GetSpans()[0]
+	if got.SpanContext.TraceID().String() == parent.TraceID() {
+		t.Error("linked async span should start a new trace")
+	}
+	if len(got.Links) != 1 || got.Links[0].SpanContext.SpanID().String() != parent.SpanID() {
+		t.Error("linked async span should link to the request span")
+	}
+}
+
+// testHTTPCollector 本地的 OTLP/HTTP collector 替身，解码 protobuf 请求体
+type testHTTPCollector struct {
+	mu        sync.Mutex
+	spans     []*tracepb.Span
+	path      string
+	headers   http.Header
+	encoding  string
+	attempts  int
+	failFirst int // 前 failFirst 次请求返回 503
+}
+
+func (c *testHTTPCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.attempts++
+	fail := c.attempts <= c.failFirst
+	c.mu.Unlock()
+	if fail {
+		http.Error(w, "collector unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	c.path = r.URL.Path
+	c.headers = r.Header.Clone()
+	c.encoding = r.Header.Get("Content-Encoding")
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	c.mu.Unlock()
+
+	resp, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(resp)
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	collector := &testHTTPCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	config := &TraceConfig{Exporter: ExporterConfig{Type: "otlp", OTLP: OTLPConfig{
+		Endpoint:    strings.TrimPrefix(server.URL, "http://"),
+		Timeout:     5,
+		Insecure:    true,
+		Protocol:    OTLPProtocolHTTPProtobuf,
+		URLPath:     "/otlp/v1/traces",
+		Headers:     map[string]string{"x-scope-orgid": "tenant-a"},
+		Compression: "gzip",
+	}}}
+	exporter, err := createOTLPExporter(config)
+	if err != nil {
+		t.Fatalf("createOTLPExporter() error = %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	if err := exportOneSpan(exporter, 5*time.Second); err != nil {
+		t.Fatalf("export over OTLP/HTTP error = %v", err)
+	}
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if len(collector.spans) != 1 || collector.spans[0].Name != "test" {
+		t.Fatalf("collector received %d spans, want 1 named test", len(collector.spans))
+	}
+	if collector.path != "/otlp/v1/traces" {
+		t.Errorf("url path = %s, want /otlp/v1/traces", collector.path)
+	}
+	if collector.headers.Get("X-Scope-OrgID") != "tenant-a" {
+		t.Error("configured headers should be sent")
+	}
+	if collector.encoding != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", collector.encoding)
+	}
+}
+
+func TestOTLPHTTPExporterTLS(t *testing.T) {
+	certs := newTestCerts(t)
+	serverCert, err := tls.LoadX509KeyPair(certs.serverCert, certs.serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := &testHTTPCollector{}
+	server := httptest.NewUnstartedServer(collector)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    certs.caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	config := &TraceConfig{Exporter: ExporterConfig{Type: "otlp", OTLP: OTLPConfig{
+		Endpoint: strings.TrimPrefix(server.URL, "https://"),
+		Timeout:  5,
+		Protocol: OTLPProtocolHTTPProtobuf,
+		TLS: TLSConfig{
+			CAFile:     certs.caFile,
+			CertFile:   certs.clientCert,
+			KeyFile:    certs.clientKey,
+			ServerName: "localhost",
+		},
+	}}}
+	exporter, err := createOTLPExporter(config)
+	if err != nil {
+		t.Fatalf("createOTLPExporter() error = %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	if err := exportOneSpan(exporter, 5*time.Second); err != nil {
+		t.Fatalf("export over OTLP/HTTPS error = %v", err)
+	}
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if len(collector.spans) != 1 {
+		t.Errorf("collector received %d spans, want 1", len(collector.spans))
+	}
+}
+
+func TestValidateOTLPConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		otlp    OTLPConfig
+		wantErr bool
+	}{
+		{"default protocol", OTLPConfig{}, false},
+		{"grpc with gzip", OTLPConfig{Protocol: "grpc", Compression: "gzip"}, false},
+		{"http with url path", OTLPConfig{Protocol: "http/protobuf", URLPath: "/v1/traces"}, false},
+		{"invalid protocol", OTLPConfig{Protocol: "http/json"}, true},
+		{"url path with grpc", OTLPConfig{Protocol: "grpc", URLPath: "/v1/traces"}, true},
+		{"relative url path", OTLPConfig{Protocol: "http/protobuf", URLPath: "v1/traces"}, true},
+		{"invalid compression", OTLPConfig{Compression: "zstd"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.otlp.Endpoint = "localhost:4318"
+			config := &TraceConfig{Enabled: true, Exporter: ExporterConfig{Type: "otlp", OTLP: tt.otlp}}
+			err := validateConfig(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOTLPExporterSecretHeaders(t *testing.T) {
+	t.Setenv("ZLTRACE_TEST_TOKEN", "s3cret")
+	tenantFile := filepath.Join(t.TempDir(), "tenant")
+	if err := os.WriteFile(tenantFile, []byte("tenant-a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	headers := map[string]string{
+		"authorization": "Bearer ${env:ZLTRACE_TEST_TOKEN}",
+		"x-tenant-id":   "file:" + tenantFile,
+	}
+
+	t.Run("grpc", func(t *testing.T) {
+		endpoint, collector := startCollector(t)
+		config := &TraceConfig{Exporter: ExporterConfig{Type: "otlp", OTLP: OTLPConfig{
+			Endpoint: endpoint, Timeout: 5, Insecure: true, Headers: headers,
+		}}}
+		exporter, err := createOTLPExporter(config)
+		if err != nil {
+			t.Fatalf("createOTLPExporter() error = %v", err)
+		}
+		defer exporter.Shutdown(context.Background())
+		if err := exportOneSpan(exporter, 5*time.Second); err != nil {
+			t.Fatalf("export error = %v", err)
+		}
+
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		if got := collector.metadata.Get("authorization"); len(got) != 1 || got[0] != "Bearer s3cret" {
+			t.Errorf("authorization metadata = %v", got)
+		}
+		if got := collector.metadata.Get("x-tenant-id"); len(got) != 1 || got[0] != "tenant-a" {
+			t.Errorf("x-tenant-id metadata = %v", got)
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+		collector := &testHTTPCollector{}
+		server := httptest.NewServer(collector)
+		defer server.Close()
+		config := &TraceConfig{Exporter: ExporterConfig{Type: "otlp", OTLP: OTLPConfig{
+			Endpoint: strings.TrimPrefix(server.URL, "http://"), Timeout: 5, Insecure: true,
+			Protocol: OTLPProtocolHTTPProtobuf, Headers: headers,
+		}}}
+		exporter, err := createOTLPExporter(config)
+		if err != nil {
+			t.Fatalf("createOTLPExporter() error = %v", err)
+		}
+		defer exporter.Shutdown(context.Background())
+		if err := exportOneSpan(exporter, 5*time.Second); err != nil {
+			t.Fatalf("export error = %v", err)
+		}
+
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		if got := collector.headers.Get("Authorization"); got != "Bearer s3cret" {
+			t.Errorf("Authorization header = %q", got)
+		}
+		if got := collector.headers.Get("X-Tenant-Id"); got != "tenant-a" {
+			t.Errorf("X-Tenant-Id header = %q", got)
+		}
+	})
+}
+
+func TestOTLPExporterRetry(t *testing.T) {
+	fastRetry := RetryConfig{
+		Enabled:         true,
+		InitialInterval: 10 * time.Millisecond,
+		MaxInterval:     50 * time.Millisecond,
+		MaxElapsedTime:  3 * time.Second,
+	}
+
+	t.Run("grpc", func(t *testing.T) {
+		endpoint, collector := startCollector(t)
+		collector.failFirst = 2
+
+		config := &TraceConfig{Exporter: ExporterConfig{
+			Type:  "otlp",
+			OTLP:  OTLPConfig{Endpoint: endpoint, Timeout: 5, Insecure: true, Compression: "gzip"},
+			Retry: fastRetry,
+		}}
+		exporter, err := createOTLPExporter(config)
+		if err != nil {
+			t.Fatalf("createOTLPExporter() error = %v", err)
+		}
+		defer exporter.Shutdown(context.Background())
+
+		if err := exportOneSpan(exporter, 5*time.Second); err != nil {
+			t.Fatalf("export should succeed after retries, error = %v", err)
+		}
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		if collector.attempts != 3 || collector.spans != 1 {
+			t.Errorf("attempts = %d, spans = %d, want 3 attempts and 1 span", collector.attempts, collector.spans)
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+		collector := &testHTTPCollector{failFirst: 2}
+		server := httptest.NewServer(collector)
+		defer server.Close()
+
+		config := &TraceConfig{Exporter: ExporterConfig{
+			Type: "otlp",
+			OTLP: OTLPConfig{
+				Endpoint: strings.TrimPrefix(server.URL, "http://"), Timeout: 5, Insecure: true,
+				Protocol: OTLPProtocolHTTPProtobuf, Compression: "gzip",
+			},
+			Retry: fastRetry,
+		}}
+		exporter, err := createOTLPExporter(config)
+		if err != nil {
+			t.Fatalf("createOTLPExporter() error = %v", err)
+		}
+		defer exporter.Shutdown(context.Background())
+
+		if err := exportOneSpan(exporter, 5*time.Second); err != nil {
+			t.Fatalf("export should succeed after retries, error = %v", err)
+		}
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		if collector.attempts != 3 || len(collector.spans) != 1 {
+			t.Errorf("attempts = %d, spans = %d, want 3 attempts and 1 span", collector.attempts, len(collector.spans))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		endpoint, collector := startCollector(t)
+		collector.failFirst = 1
+
+		config := &TraceConfig{Exporter: ExporterConfig{
+			Type:  "otlp",
+			OTLP:  OTLPConfig{Endpoint: endpoint, Timeout: 5, Insecure: true},
+			Retry: RetryConfig{Enabled: false},
+		}}
+		exporter, err := createOTLPExporter(config)
+		if err != nil {
+			t.Fatalf("createOTLPExporter() error = %v", err)
+		}
+		defer exporter.Shutdown(context.Background())
+
+		if err := exportOneSpan(exporter, 5*time.Second); err == nil {
+			t.Error("export should fail without retry")
+		}
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		if collector.attempts != 1 {
+			t.Errorf("attempts = %d, want 1", collector.attempts)
+		}
+	})
+}
+
+func TestOTLPGRPCExporterTimeout(t *testing.T) {
+	endpoint, collector := startCollector(t)
+	collector.delay = 10 * time.Second
+
+	config := &TraceConfig{Exporter: ExporterConfig{
+		Type:  "otlp",
+		OTLP:  OTLPConfig{Endpoint: endpoint, Timeout: 1, Insecure: true},
+		Retry: RetryConfig{Enabled: false},
+	}}
+	exporter, err := createOTLPExporter(config)
+	if err != nil {
+		t.Fatalf("createOTLPExporter() error = %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	// exporter.otlp.timeout 限制单次导出的时间，而不是使用 SDK 默认的 10s
+	start := time.Now()
+	if err := exportOneSpan(exporter, 20*time.Second); err == nil {
+		t.Fatal("export should time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("export took %v, want about 1s", elapsed)
+	}
+}
+
+func TestValidateRetryConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		retry   RetryConfig
+		wantErr bool
+	}{
+		{"default", defaultRetryConfig(), false},
+		{"disabled", RetryConfig{}, false},
+		{"missing unit", RetryConfig{Enabled: true, InitialInterval: 5, MaxInterval: 30 * time.Second, MaxElapsedTime: time.Minute}, true},
+		{"max below initial", RetryConfig{Enabled: true, InitialInterval: time.Minute, MaxInterval: time.Second, MaxElapsedTime: time.Minute}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &TraceConfig{Enabled: true, Exporter: ExporterConfig{
+				Type:  "otlp",
+				OTLP:  OTLPConfig{Endpoint: "localhost:4317"},
+				Retry: tt.retry,
+			}}
+			err := validateConfig(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBatchSpanProcessorOptions(t *testing.T) {
+	apply := func(config BatchConfig) sdktrace.BatchSpanProcessorOptions {
+		var o sdktrace.BatchSpanProcessorOptions
+		for _, opt := range batchSpanProcessorOptions(config) {
+			opt(&o)
+		}
+		return o
+	}
+
+	got := apply(BatchConfig{
+		BatchSize:        128,
+		Timeout:          5,
+		ScheduleDelayMs:  200,
+		ExportTimeout:    10,
+		MaxQueueSize:     1024,
+		BlockOnQueueFull: true,
+	})
+	want := sdktrace.BatchSpanProcessorOptions{
+		MaxQueueSize:       1024,
+		BatchTimeout:       200 * time.Millisecond,
+		ExportTimeout:      10 * time.Second,
+		MaxExportBatchSize: 128,
+		BlockOnQueueFull:   true,
+	}
+	if got != want {
+		t.Errorf("options = %+v, want %+v", got, want)
+	}
+
+	// 未配置 schedule_delay_ms 时使用 timeout（秒）
+	if got := apply(BatchConfig{Timeout: 3}); got.BatchTimeout != 3*time.Second {
+		t.Errorf("BatchTimeout = %v, want 3s", got.BatchTimeout)
+	}
+	// 零值不设置，使用 SDK 默认值
+	if got := apply(BatchConfig{}); got != (sdktrace.BatchSpanProcessorOptions{}) {
+		t.Errorf("zero config should not set options, got %+v", got)
+	}
+}
+
+func TestLoggingExporterFields(t *testing.T) {
+	original := zllog.GetLogger()
+	defer zllog.SetLogger(original)
+	recorder := &recordingLogger{}
+	zllog.SetLogger(recorder)
+
+	tracer, memory := newSyncTestTracer()
+	defer tracer.Close()
+	parent, ctx := tracer.StartSpan(context.Background(), "parent")
+	child, _ := StartSpanWithOptions(tracer, ctx, "child", WithSpanKind(SpanKindClient))
+	child.SetTag("http.status_code", 500)
+	child.AddEvent("retry", Attr("attempt", 2))
+	child.SetError(errors.New("boom"))
+	child.Finish()
+	parent.Finish()
+
+	exporter := NewLoggingExporter(WithLogLevel("info"))
+	if err := exporter.ExportSpans(context.Background(), memory.GetSpans().Snapshots()[:1]); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+
+	if recorder.level != "info" {
+		t.Errorf("level = %s, want info", recorder.level)
+	}
+	if got, _ := recorder.field("parent_span_id"); got != parent.SpanID() {
+		t.Errorf("parent_span_id = %v, want %s", got, parent.SpanID())
+	}
+	if got, _ := recorder.field("kind"); got != "client" {
+		t.Errorf("kind = %v, want client", got)
+	}
+	if got, _ := recorder.field("status"); got != "Error" {
+		t.Errorf("status = %v, want Error", got)
+	}
+	attrs, _ := recorder.field("attributes")
+	if attrs.(map[string]interface{})["http.status_code"] != int64(500) {
+		t.Errorf("attributes = %v", attrs)
+	}
+	events, _ := recorder.field("events")
+	if list := events.([]map[string]interface{}); len(list) != 2 || list[0]["name"] != "retry" {
+		t.Errorf("events = %v", events)
+	}
+	if _, ok := recorder.field("resource"); !ok {
+		t.Error("resource should be logged")
+	}
+}
+
+func TestLoggingExporterFilters(t *testing.T) {
+	original := zllog.GetLogger()
+	defer zllog.SetLogger(original)
+	recorder := &recordingLogger{}
+	zllog.SetLogger(recorder)
+
+	now := time.Now()
+	spans := tracetest.SpanStubs{
+		{Name: "ok-fast", StartTime: now, EndTime: now.Add(time.Millisecond)},
+		{Name: "ok-slow", StartTime: now, EndTime: now.Add(time.Second)},
+		{Name: "error-fast", StartTime: now, EndTime: now.Add(time.Millisecond), Status: sdktrace.Status{Code: codes.Error}},
+	}.Snapshots()
+
+	tests := []struct {
+		name string
+		opts []LoggingExporterOption
+		want int
+	}{
+		{"no filter", nil, 3},
+		{"only errors", []LoggingExporterOption{WithOnlyErrorSpans()}, 1},
+		{"slow threshold", []LoggingExporterOption{WithSlowSpanThreshold(500 * time.Millisecond)}, 1},
+		{"errors or slow", []LoggingExporterOption{WithOnlyErrorSpans(), WithSlowSpanThreshold(500 * time.Millisecond)}, 2},
+	}
+	for _, tt := range tests {
+		recorder.count = 0
+		if err := NewLoggingExporter(tt.opts...).ExportSpans(context.Background(), spans); err != nil {
+			t.Fatalf("%s: ExportSpans() error = %v", tt.name, err)
+		}
+		if recorder.count != tt.want {
+			t.Errorf("%s: logged %d spans, want %d", tt.name, recorder.count, tt.want)
+		}
+	}
+}
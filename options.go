@@ -0,0 +1,134 @@
+package zltrace
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ============================================================================
+// StartSpan 可选参数
+// ============================================================================
+
+// Attribute span 属性（键值对）
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr 创建一个 span 属性
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// StartSpanConfig 启动 span 时的配置，由 StartSpanOption 填充
+// 自定义 Tracer 实现 OptionsTracer 时，通过 NewStartSpanConfig 解析选项
+type StartSpanConfig struct {
+	// Kind span 类型（默认 SpanKindInternal）
+	Kind SpanKind
+	// Attributes 创建时就设置的属性（采样器可以看到）
+	Attributes []Attribute
+	// Links 关联的 span（非父子关系，例如批量消费时关联每条消息的上游 span）
+	Links []Span
+	// StartTime span 开始时间（零值表示当前时间）
+	StartTime time.Time
+	// NewRoot 忽略 context 中的父 span，创建新的 trace
+	NewRoot bool
+}
+
+// StartSpanOption 启动 span 时的可选参数
+type StartSpanOption func(*StartSpanConfig)
+
+// NewStartSpanConfig 依次应用 opts，返回最终配置
+func NewStartSpanConfig(opts ...StartSpanOption) StartSpanConfig {
+	var config StartSpanConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&config)
+		}
+	}
+	return config
+}
+
+// WithAttributes 在创建 span 时设置属性
+func WithAttributes(attrs ...Attribute) StartSpanOption {
+	return func(c *StartSpanConfig) {
+		c.Attributes = append(c.Attributes, attrs...)
+	}
+}
+
+// WithLinks 关联其他 span（不作为父 span）
+func WithLinks(spans ...Span) StartSpanOption {
+	return func(c *StartSpanConfig) {
+		c.Links = append(c.Links, spans...)
+	}
+}
+
+// WithStartTime 指定 span 的开始时间
+func WithStartTime(t time.Time) StartSpanOption {
+	return func(c *StartSpanConfig) {
+		c.StartTime = t
+	}
+}
+
+// WithSpanKind 指定 span 类型
+func WithSpanKind(kind SpanKind) StartSpanOption {
+	return func(c *StartSpanConfig) {
+		c.Kind = kind
+	}
+}
+
+// WithNewRoot 忽略 context 中的父 span，开启一个新的 trace
+func WithNewRoot() StartSpanOption {
+	return func(c *StartSpanConfig) {
+		c.NewRoot = true
+	}
+}
+
+// OptionsTracer 支持 StartSpanOption 的 Tracer（可选接口）
+// OTELTracer 和 noOpTracer 实现了此接口；自定义 Tracer 可以按需实现
+type OptionsTracer interface {
+	// StartSpanWithOptions 使用可选参数启动一个新的 span
+	StartSpanWithOptions(ctx context.Context, operationName string, opts ...StartSpanOption) (Span, context.Context)
+}
+
+// StartSpanWithOptions 使用可选参数启动 span
+//
+// 如果 tracer 实现了 OptionsTracer，由 tracer 原生处理所有参数；
+// 否则退化为 StartSpan，属性和 span 类型通过 SetTags/SetTag 补充，
+// Links 和 StartTime 无法表达，会被忽略。
+//
+// 使用示例：
+//
+//	span, ctx := zltrace.StartSpanWithOptions(zltrace.GetSafeTracer(), ctx, "order.create",
+//	    zltrace.WithSpanKind(zltrace.SpanKindInternal),
+//	    zltrace.WithAttributes(zltrace.Attr("order.id", orderID)))
+//	defer span.Finish()
+func StartSpanWithOptions(tracer Tracer, ctx context.Context, operationName string, opts ...StartSpanOption) (Span, context.Context) {
+	if ot, ok := tracer.(OptionsTracer); ok {
+		return ot.StartSpanWithOptions(ctx, operationName, opts...)
+	}
+
+	config := NewStartSpanConfig(opts...)
+	if config.NewRoot {
+		ctx = contextWithoutSpan(ctx)
+	}
+
+	span, spanCtx := tracer.StartSpan(ctx, operationName)
+	if config.Kind != SpanKindInternal {
+		span.SetTag("span.kind", config.Kind.String())
+	}
+	if len(config.Attributes) > 0 {
+		span.SetTags(config.Attributes...)
+	}
+	return span, spanCtx
+}
+
+// contextWithoutSpan 返回屏蔽了父 span 的 context（保留其他值、deadline 和取消信号）
+func contextWithoutSpan(ctx context.Context) context.Context {
+	if ctx.Value(_spanKey) != nil {
+		ctx = context.WithValue(ctx, _spanKey, nil)
+	}
+	return trace.ContextWithSpanContext(ctx, trace.SpanContext{})
+}
@@ -0,0 +1,117 @@
+package zltrace
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ============================================================================
+// rateLimitingSampler - 每秒最多采样 N 个 trace
+// ============================================================================
+
+// SamplingProbabilityKey 限流采样时记录在根 span 上的实际采样概率
+//
+// 追踪后端可以用 1/probability 估算真实的请求量。
+const SamplingProbabilityKey = "sampling.probability"
+
+// rateLimitingSampler 令牌桶限流采样器
+//
+// 令牌以 tracesPerSecond 的速度补充，桶容量为 1 秒的量（至少 1 个），
+// 因此允许 1 秒内的突发。有令牌时采样并消耗一个令牌，否则丢弃。
+//
+// 只作为根采样器使用（由 ParentBased 包装）：同一个 trace 的子 span 跟随父 span 的决定，
+// 不消耗令牌，也不会把 trace 截断。
+type rateLimitingSampler struct {
+	tracesPerSecond float64
+	capacity        float64
+	now             func() time.Time // 测试时替换
+
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+
+	// 按 1 秒的窗口统计请求数，用于估算实际采样概率
+	windowStart time.Time
+	seen        int // 当前窗口的请求数
+	previous    int // 上一个完整窗口的请求数
+}
+
+func newRateLimitingSampler(tracesPerSecond float64) *rateLimitingSampler {
+	return &rateLimitingSampler{
+		tracesPerSecond: tracesPerSecond,
+		capacity:        math.Max(tracesPerSecond, 1),
+		tokens:          math.Max(tracesPerSecond, 1),
+		now:             time.Now,
+	}
+}
+
+// ShouldSample 有令牌时采样（实现 Sampler 接口）
+func (s *rateLimitingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	traceState := trace.SpanContextFromContext(p.ParentContext).TraceState()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.refill(now)
+	s.count(now)
+
+	if s.tokens < 1 {
+		return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: traceState}
+	}
+	s.tokens--
+
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordAndSample,
+		Attributes: []attribute.KeyValue{attribute.Float64(SamplingProbabilityKey, s.probability())},
+		Tracestate: traceState,
+	}
+}
+
+// Description 采样器描述（实现 Sampler 接口）
+func (s *rateLimitingSampler) Description() string {
+	return fmt.Sprintf("RateLimitingSampler{%g}", s.tracesPerSecond)
+}
+
+// refill 按经过的时间补充令牌
+func (s *rateLimitingSampler) refill(now time.Time) {
+	if s.lastRefill.IsZero() {
+		s.lastRefill = now
+		return
+	}
+	if elapsed := now.Sub(s.lastRefill); elapsed > 0 {
+		s.tokens = math.Min(s.capacity, s.tokens+elapsed.Seconds()*s.tracesPerSecond)
+		s.lastRefill = now
+	}
+}
+
+// count 统计当前窗口的请求数，窗口满 1 秒后滚动
+func (s *rateLimitingSampler) count(now time.Time) {
+	switch elapsed := now.Sub(s.windowStart); {
+	case s.windowStart.IsZero():
+		s.windowStart = now
+	case elapsed >= 2*time.Second:
+		// 中间有完整的窗口没有请求
+		s.previous, s.seen, s.windowStart = 0, 0, now
+	case elapsed >= time.Second:
+		s.previous, s.seen, s.windowStart = s.seen, 0, now
+	}
+	s.seen++
+}
+
+// probability 估算实际采样概率：每秒允许的 trace 数 / 每秒的请求数
+//
+// 请求数取上一个完整窗口和当前窗口中较大的一个，流量突增时可以立即反映出来。
+func (s *rateLimitingSampler) probability() float64 {
+	seen := s.previous
+	if s.seen > seen {
+		seen = s.seen
+	}
+	return math.Min(1, s.tracesPerSecond/float64(seen))
+}
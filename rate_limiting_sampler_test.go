@@ -0,0 +1,161 @@
+package zltrace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestRateLimitingSampler 创建使用假时钟的限流采样器，返回推进时钟的函数
+func newTestRateLimitingSampler(tracesPerSecond float64) (*rateLimitingSampler, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sampler := newRateLimitingSampler(tracesPerSecond)
+	sampler.now = func() time.Time { return now }
+	return sampler, func(d time.Duration) { now = now.Add(d) }
+}
+
+// sampleRoots 对 n 个新 trace 做采样决定，返回采样数量和最后一个被采样的概率
+func sampleRoots(sampler sdktrace.Sampler, n int) (sampled int, probability float64) {
+	for i := 0; i < n; i++ {
+		result := sampler.ShouldSample(sdktrace.SamplingParameters{ParentContext: context.Background(), Name: "op"})
+		if result.Decision != sdktrace.RecordAndSample {
+			continue
+		}
+		sampled++
+		for _, kv := range result.Attributes {
+			if kv.Key == SamplingProbabilityKey {
+				probability = kv.Value.AsFloat64()
+			}
+		}
+	}
+	return sampled, probability
+}
+
+func TestRateLimitingSampler(t *testing.T) {
+	sampler, advance := newTestRateLimitingSampler(2)
+
+	// 初始桶是满的：允许 1 秒的突发
+	if sampled, p := sampleRoots(sampler, 5); sampled != 2 || p != 1 {
+		t.Errorf("burst: sampled = %d, probability = %v, want 2 and 1", sampled, p)
+	}
+
+	// 1 秒后补充 2 个令牌；上一秒有 5 个请求，实际采样概率 2/5
+	advance(time.Second)
+	if sampled, p := sampleRoots(sampler, 5); sampled != 2 || p != 0.4 {
+		t.Errorf("second window: sampled = %d, probability = %v, want 2 and 0.4", sampled, p)
+	}
+
+	// 半秒补充 1 个令牌
+	advance(500 * time.Millisecond)
+	if sampled, _ := sampleRoots(sampler, 3); sampled != 1 {
+		t.Errorf("after 500ms: sampled = %d, want 1", sampled)
+	}
+
+	// 长时间空闲后令牌不超过桶容量，概率恢复为 1
+	advance(10 * time.Second)
+	if sampled, p := sampleRoots(sampler, 2); sampled != 2 || p != 1 {
+		t.Errorf("after idle: sampled = %d, probability = %v, want 2 and 1", sampled, p)
+	}
+	if sampled, _ := sampleRoots(sampler, 1); sampled != 0 {
+		t.Error("bucket should not exceed its capacity")
+	}
+}
+
+func TestRateLimitingSamplerFractionalRate(t *testing.T) {
+	// 每 2 秒 1 个 trace
+	sampler, advance := newTestRateLimitingSampler(0.5)
+
+	if sampled, _ := sampleRoots(sampler, 3); sampled != 1 {
+		t.Errorf("sampled = %d, want 1", sampled)
+	}
+	advance(time.Second)
+	if sampled, _ := sampleRoots(sampler, 1); sampled != 0 {
+		t.Errorf("after 1s: sampled = %d, want 0", sampled)
+	}
+	advance(time.Second)
+	if sampled, _ := sampleRoots(sampler, 1); sampled != 1 {
+		t.Errorf("after 2s: sampled = %d, want 1", sampled)
+	}
+}
+
+func TestRateLimitingSamplerComposesWithParentBased(t *testing.T) {
+	sampler, err := createSampler(SamplerConfig{Type: "rate_limiting", TracesPerSecond: 1})
+	if err != nil {
+		t.Fatalf("createSampler() error = %v", err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sampler))
+	defer tp.Shutdown(context.Background())
+	tracer := tp.Tracer("test")
+
+	// 第一个 trace 消耗唯一的令牌，子 span 跟随父 span 不消耗令牌
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	root.End()
+
+	// 令牌用完：新 trace 不采样
+	_, dropped := tracer.Start(context.Background(), "dropped")
+	dropped.End()
+
+	// 上游已采样的请求不受限流影响
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled, Remote: true,
+	})
+	_, upstream := tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "upstream")
+	upstream.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("exported %d spans, want root, child and upstream", len(spans))
+	}
+	for _, span := range spans {
+		hasProbability := false
+		for _, kv := range span.Attributes {
+			hasProbability = hasProbability || kv.Key == SamplingProbabilityKey
+		}
+		if want := span.Name == "root"; hasProbability != want {
+			t.Errorf("span %q: has %s = %v, want %v", span.Name, SamplingProbabilityKey, hasProbability, want)
+		}
+	}
+}
+
+func TestConfigLoaderRateLimitingSampler(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+sampler:
+  type: parent_based
+  root:
+    type: rate_limiting
+    traces_per_second: 50
+`
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.Sampler.Root.Type != "rate_limiting" || config.Sampler.Root.TracesPerSecond != 50 {
+		t.Errorf("root sampler = %+v", config.Sampler.Root)
+	}
+
+	invalid := []SamplerConfig{
+		{Type: "rate_limiting"},
+		{Type: "parent_based", Root: RootSamplerConfig{Type: "rate_limiting", TracesPerSecond: -1}},
+	}
+	for _, sampler := range invalid {
+		if err := validateConfig(&TraceConfig{Enabled: true, Sampler: sampler, Exporter: ExporterConfig{Type: "stdout"}}); err == nil {
+			t.Errorf("validateConfig() should reject %+v", sampler)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package zltrace
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/zlxdbj/zllog"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ============================================================================
+// ruleBasedSampler - 按 span 名称、类型和属性选择采样规则
+// ============================================================================
+
+// SamplingRuleKey 按规则采样时记录在根 span 上的规则名称（规则配置了 name 时）
+const SamplingRuleKey = "sampling.rule"
+
+// ruleBasedSampler 按顺序匹配规则，使用第一条匹配规则的采样率，都不匹配时使用默认规则
+//
+// 匹配条件来自创建 span 时可见的信息：span 名称、类型和初始属性（如 http.route、kafka.topic），
+// 创建后通过 SetTag 设置的属性采样器看不到。
+type ruleBasedSampler struct {
+	rules    []samplingRule
+	fallback samplingRule
+}
+
+// samplingRule 编译后的采样规则
+type samplingRule struct {
+	name       string
+	spanName   *regexp.Regexp // nil 表示不限制
+	kind       trace.SpanKind // SpanKindUnspecified 表示不限制
+	attributes map[attribute.Key]*regexp.Regexp
+	sampler    sdktrace.Sampler
+}
+
+func newRuleBasedSampler(rules []SamplingRule, fallback SamplingRule) (*ruleBasedSampler, error) {
+	s := &ruleBasedSampler{}
+	for i, rule := range rules {
+		compiled, err := compileSamplingRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("trace.sampler.rules[%d]: %w", i, err)
+		}
+		s.rules = append(s.rules, compiled)
+	}
+
+	if fallback.SpanName != "" || fallback.SpanNameRegex != "" || fallback.SpanKind != "" || len(fallback.Attributes) > 0 {
+		return nil, fmt.Errorf("trace.sampler.default only supports ratio and traces_per_second")
+	}
+	if fallback.Ratio == nil && fallback.TracesPerSecond == 0 {
+		// 没有配置默认规则时全量采样
+		s.fallback = samplingRule{name: fallback.Name, sampler: sdktrace.AlwaysSample()}
+		return s, nil
+	}
+	compiled, err := compileSamplingRule(fallback)
+	if err != nil {
+		return nil, fmt.Errorf("trace.sampler.default: %w", err)
+	}
+	s.fallback = compiled
+	return s, nil
+}
+
+// compileSamplingRule 编译规则的匹配条件并创建规则的采样器
+func compileSamplingRule(rule SamplingRule) (samplingRule, error) {
+	compiled := samplingRule{name: rule.Name}
+
+	switch {
+	case rule.SpanName != "" && rule.SpanNameRegex != "":
+		return compiled, fmt.Errorf("span_name and span_name_regex cannot be used together")
+	case rule.SpanName != "":
+		compiled.spanName = globToRegexp(rule.SpanName)
+	case rule.SpanNameRegex != "":
+		re, err := regexp.Compile(rule.SpanNameRegex)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid span_name_regex %q: %w", rule.SpanNameRegex, err)
+		}
+		compiled.spanName = re
+	}
+
+	if rule.SpanKind != "" {
+		kind, ok := parseSpanKind(rule.SpanKind)
+		if !ok {
+			return compiled, fmt.Errorf("invalid span_kind: %s (must be internal, server, client, producer, or consumer)", rule.SpanKind)
+		}
+		compiled.kind = kind
+	}
+
+	if len(rule.Attributes) > 0 {
+		compiled.attributes = make(map[attribute.Key]*regexp.Regexp, len(rule.Attributes))
+		for key, pattern := range rule.Attributes {
+			compiled.attributes[attribute.Key(key)] = globToRegexp(pattern)
+		}
+	}
+
+	switch {
+	case rule.Ratio != nil && rule.TracesPerSecond != 0:
+		return compiled, fmt.Errorf("ratio and traces_per_second cannot be used together")
+	case rule.Ratio != nil:
+		if err := validateSamplerRatio("ratio", *rule.Ratio); err != nil {
+			return compiled, err
+		}
+		compiled.sampler = sdktrace.TraceIDRatioBased(*rule.Ratio)
+	case rule.TracesPerSecond > 0:
+		compiled.sampler = newRateLimitingSampler(rule.TracesPerSecond)
+	default:
+		return compiled, fmt.Errorf("ratio or traces_per_second (greater than 0) is required")
+	}
+	return compiled, nil
+}
+
+// ShouldSample 使用第一条匹配规则的采样器（实现 Sampler 接口）
+func (s *ruleBasedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	rule := &s.fallback
+	for i := range s.rules {
+		if s.rules[i].matches(p) {
+			rule = &s.rules[i]
+			break
+		}
+	}
+
+	result := rule.sampler.ShouldSample(p)
+	if rule.name != "" && result.Decision == sdktrace.RecordAndSample {
+		result.Attributes = append(result.Attributes, attribute.String(SamplingRuleKey, rule.name))
+	}
+	return result
+}
+
+// Description 采样器描述（实现 Sampler 接口）
+func (s *ruleBasedSampler) Description() string {
+	descriptions := make([]string, 0, len(s.rules)+1)
+	for _, rule := range s.rules {
+		descriptions = append(descriptions, rule.sampler.Description())
+	}
+	descriptions = append(descriptions, "default:"+s.fallback.sampler.Description())
+	return fmt.Sprintf("RuleBasedSampler{%s}", strings.Join(descriptions, ","))
+}
+
+// matches 判断规则的所有条件是否都满足
+func (r *samplingRule) matches(p sdktrace.SamplingParameters) bool {
+	if r.spanName != nil && !r.spanName.MatchString(p.Name) {
+		return false
+	}
+	if r.kind != trace.SpanKindUnspecified && r.kind != p.Kind {
+		return false
+	}
+	for key, pattern := range r.attributes {
+		if !attributeMatches(p.Attributes, key, pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+func attributeMatches(attrs []attribute.KeyValue, key attribute.Key, pattern *regexp.Regexp) bool {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return pattern.MatchString(kv.Value.Emit())
+		}
+	}
+	return false
+}
+
+// globToRegexp 将通配符转换为正则表达式：* 匹配任意字符（包括 /），? 匹配单个字符
+func globToRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// parseSpanKind 解析配置中的 span 类型
+func parseSpanKind(kind string) (trace.SpanKind, bool) {
+	switch kind {
+	case "internal":
+		return trace.SpanKindInternal, true
+	case "server":
+		return trace.SpanKindServer, true
+	case "client":
+		return trace.SpanKindClient, true
+	case "producer":
+		return trace.SpanKindProducer, true
+	case "consumer":
+		return trace.SpanKindConsumer, true
+	default:
+		return trace.SpanKindUnspecified, false
+	}
+}
+
+// ============================================================================
+// 采样配置热更新
+// ============================================================================
+
+// reloadableSampler 可以在运行中替换的采样器
+//
+// TracerProvider 创建后不能更换采样器，InitOpenTelemetryTracer 使用这个包装，
+// ReloadSampler 时只替换内部的采样器。
+type reloadableSampler struct {
+	mu      sync.RWMutex
+	sampler sdktrace.Sampler
+}
+
+func newReloadableSampler(sampler sdktrace.Sampler) *reloadableSampler {
+	return &reloadableSampler{sampler: sampler}
+}
+
+func (s *reloadableSampler) load() sdktrace.Sampler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sampler
+}
+
+func (s *reloadableSampler) store(sampler sdktrace.Sampler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sampler = sampler
+}
+
+// ShouldSample 使用当前的采样器（实现 Sampler 接口）
+func (s *reloadableSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.load().ShouldSample(p)
+}
+
+// Description 当前采样器的描述（实现 Sampler 接口）
+func (s *reloadableSampler) Description() string {
+	return s.load().Description()
+}
+
+// SetSamplerConfig 更新采样配置，新创建的 span 立即使用新配置
+//
+// 只支持 InitOpenTelemetryTracer 创建的 Tracer；配置无效时返回错误，原配置保持不变。
+// 限流采样器的令牌桶会重新开始计数。
+func (t *OTELTracer) SetSamplerConfig(config SamplerConfig) error {
+	if t.sampler == nil {
+		return fmt.Errorf("Tracer 不是由 InitOpenTelemetryTracer 创建，无法更新采样配置")
+	}
+	if err := validateSamplerConfig(config); err != nil {
+		return fmt.Errorf("采样配置无效: %w", err)
+	}
+	sampler, err := createSampler(config)
+	if err != nil {
+		return fmt.Errorf("创建采样器失败: %w", err)
+	}
+	t.sampler.store(sampler)
+
+	zllog.Info(context.Background(), "trace", "采样配置已更新",
+		zllog.String("sampler", sampler.Description()))
+	return nil
+}
+
+// ReloadSampler 重新读取配置文件中的 sampler 配置并立即生效
+//
+// 只更新采样配置，exporter 等其他配置需要重启才能生效。配置文件不存在、无法解析或配置无效时
+// 返回错误，继续使用当前的采样配置。可以在收到 SIGHUP 时调用：
+//
+//	signal.Notify(ch, syscall.SIGHUP)
+//	go func() {
+//	    for range ch {
+//	        if err := zltrace.ReloadSampler(); err != nil {
+//	            log.Printf("reload sampler: %v", err)
+//	        }
+//	    }
+//	}()
+func ReloadSampler() error {
+	tracer, ok := GetTracer().(*OTELTracer)
+	if !ok {
+		return fmt.Errorf("追踪系统未初始化，无法更新采样配置")
+	}
+	return tracer.reloadSampler(NewConfigLoader())
+}
+
+// reloadSampler 使用 loader 读取配置文件并更新采样配置
+//
+// 和 Load 不同，找不到配置文件时不使用默认配置（默认全量采样），避免配置文件被误删后采样配置被静默替换。
+func (t *OTELTracer) reloadSampler(loader *ConfigLoader) error {
+	config, err := loader.loadFromFiles()
+	if err != nil {
+		return fmt.Errorf("读取追踪配置失败: %w", err)
+	}
+	if config == nil {
+		return fmt.Errorf("读取追踪配置失败: 没有找到配置文件")
+	}
+	return t.SetSamplerConfig(config.Sampler)
+}
@@ -0,0 +1,306 @@
+package zltrace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func ratio(r float64) *float64 { return &r }
+
+func TestRuleBasedSampler(t *testing.T) {
+	sampler, err := newRuleBasedSampler([]SamplingRule{
+		{Name: "health", SpanName: "GET /health*", SpanKind: "server", Ratio: ratio(0)},
+		{Name: "metrics", SpanNameRegex: `^GET /(metrics|prometheus)$`, Ratio: ratio(0)},
+		{Name: "audit", Attributes: map[string]string{"kafka.topic": "audit-*"}, Ratio: ratio(0)},
+		{Name: "payment", Attributes: map[string]string{"http.route": "/api/pay/:id"}, Ratio: ratio(1)},
+		{Name: "all-server", SpanKind: "server", Ratio: ratio(1)},
+	}, SamplingRule{Ratio: ratio(0)})
+	if err != nil {
+		t.Fatalf("newRuleBasedSampler() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		span     string
+		kind     trace.SpanKind
+		attrs    []attribute.KeyValue
+		wantRule string // 空表示不采样
+	}{
+		{"health check", "GET /health/live", trace.SpanKindServer, nil, ""},
+		{"health kind mismatch falls through", "GET /health", trace.SpanKindClient, nil, ""},
+		{"metrics regex", "GET /metrics", trace.SpanKindServer, nil, ""},
+		{"audit topic", "Kafka/Consume", trace.SpanKindConsumer, []attribute.KeyValue{attribute.String("kafka.topic", "audit-login")}, ""},
+		{"payment route", "POST /api/pay/42", trace.SpanKindServer, []attribute.KeyValue{attribute.String("http.route", "/api/pay/:id")}, "payment"},
+		{"later rule", "GET /orders", trace.SpanKindServer, nil, "all-server"},
+		{"default", "Kafka/Consume", trace.SpanKindConsumer, []attribute.KeyValue{attribute.String("kafka.topic", "orders")}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := sampler.ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: context.Background(),
+				TraceID:       trace.TraceID{1},
+				Name:          tt.span,
+				Kind:          tt.kind,
+				Attributes:    tt.attrs,
+			})
+			sampled := result.Decision == sdktrace.RecordAndSample
+			if sampled != (tt.wantRule != "") {
+				t.Fatalf("sampled = %v, want rule %q", sampled, tt.wantRule)
+			}
+			if sampled && (len(result.Attributes) != 1 || result.Attributes[0] != attribute.String(SamplingRuleKey, tt.wantRule)) {
+				t.Errorf("attributes = %v, want %s=%s", result.Attributes, SamplingRuleKey, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestRuleBasedSamplerRateLimit(t *testing.T) {
+	sampler, err := newRuleBasedSampler([]SamplingRule{
+		{SpanName: "op", TracesPerSecond: 1}, // sampleRoots 使用的 span 名称
+	}, SamplingRule{})
+	if err != nil {
+		t.Fatalf("newRuleBasedSampler() error = %v", err)
+	}
+
+	if sampled, _ := sampleRoots(sampler, 1); sampled != 1 {
+		t.Error("first message should be sampled")
+	}
+	if sampled, _ := sampleRoots(sampler, 1); sampled != 0 {
+		t.Error("rate limit of the matching rule should apply")
+	}
+
+	// 没有配置默认规则时全量采样
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{ParentContext: context.Background(), Name: "GET /orders"})
+	if result.Decision != sdktrace.RecordAndSample {
+		t.Error("spans matching no rule should be sampled by default")
+	}
+}
+
+// testRouteHTTPHandler 额外实现 HTTPRouteHandler 接口
+type testRouteHTTPHandler struct {
+	testHTTPHandler
+	route string
+}
+
+func (h *testRouteHTTPHandler) GetRoute() string { return h.route }
+
+func TestRuleBasedSamplerHTTPRoute(t *testing.T) {
+	sampler, err := createSampler(SamplerConfig{
+		Type: "rule_based",
+		Rules: []SamplingRule{
+			{Name: "health", SpanKind: "server", Attributes: map[string]string{"http.route": "/health"}, Ratio: ratio(0)},
+			{Name: "payment", Attributes: map[string]string{"http.route": "/api/pay/*"}, Ratio: ratio(1)},
+		},
+		Default: SamplingRule{Ratio: ratio(0)},
+	})
+	if err != nil {
+		t.Fatalf("createSampler() error = %v", err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := newOTELTracer(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sampler)), nil, "test")
+	defer tracer.Close()
+	RegisterTracer(tracer)
+	defer RegisterTracer(nil)
+
+	for _, h := range []*testRouteHTTPHandler{
+		{testHTTPHandler{method: "GET", url: "/health", headers: map[string]string{}}, "/health"},
+		{testHTTPHandler{method: "POST", url: "/api/pay/42", headers: map[string]string{}}, "/api/pay/:id"},
+		{testHTTPHandler{method: "GET", url: "/orders", headers: map[string]string{}}, "/orders"},
+	} {
+		TraceHTTPRequest(context.Background(), h, func() {})
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "POST /api/pay/42" {
+		t.Fatalf("exported spans = %v, want only the payment request", spans)
+	}
+	attrs := make(map[attribute.Key]string)
+	for _, kv := range spans[0].Attributes {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	if attrs["http.route"] != "/api/pay/:id" || attrs[SamplingRuleKey] != "payment" {
+		t.Errorf("attributes = %v", spans[0].Attributes)
+	}
+}
+
+func TestValidateRuleBasedSampler(t *testing.T) {
+	tests := []struct {
+		name    string
+		sampler SamplerConfig
+		wantErr bool
+	}{
+		{"valid", SamplerConfig{Type: "rule_based", Rules: []SamplingRule{{SpanName: "GET /health", Ratio: ratio(0)}}, Default: SamplingRule{TracesPerSecond: 10}}, false},
+		{"no rules", SamplerConfig{Type: "rule_based"}, false},
+		{"missing rate", SamplerConfig{Type: "rule_based", Rules: []SamplingRule{{SpanName: "GET /health"}}}, true},
+		{"ratio and rate", SamplerConfig{Type: "rule_based", Rules: []SamplingRule{{Ratio: ratio(0.5), TracesPerSecond: 1}}}, true},
+		{"ratio out of range", SamplerConfig{Type: "rule_based", Rules: []SamplingRule{{Ratio: ratio(2)}}}, true},
+		{"invalid regex", SamplerConfig{Type: "rule_based", Rules: []SamplingRule{{SpanNameRegex: "(", Ratio: ratio(1)}}}, true},
+		{"glob and regex", SamplerConfig{Type: "rule_based", Rules: []SamplingRule{{SpanName: "a", SpanNameRegex: "a", Ratio: ratio(1)}}}, true},
+		{"invalid kind", SamplerConfig{Type: "rule_based", Rules: []SamplingRule{{SpanKind: "http", Ratio: ratio(1)}}}, true},
+		{"default with matcher", SamplerConfig{Type: "rule_based", Default: SamplingRule{SpanName: "a", Ratio: ratio(1)}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(&TraceConfig{Enabled: true, Sampler: tt.sampler, Exporter: ExporterConfig{Type: "stdout"}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigLoaderRuleBasedSampler(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+sampler:
+  type: rule_based
+  rules:
+    - name: health
+      span_name: "GET /health*"
+      span_kind: server
+      ratio: 0
+    - name: audit
+      attributes:
+        kafka.topic: "audit-*"
+      traces_per_second: 1
+  default:
+    ratio: 0.1
+`
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	rules := config.Sampler.Rules
+	if len(rules) != 2 {
+		t.Fatalf("rules = %+v, want 2 rules", rules)
+	}
+	if rules[0].SpanName != "GET /health*" || rules[0].SpanKind != "server" || rules[0].Ratio == nil || *rules[0].Ratio != 0 {
+		t.Errorf("rules[0] = %+v", rules[0])
+	}
+	if rules[1].Attributes["kafka.topic"] != "audit-*" || rules[1].TracesPerSecond != 1 || rules[1].Ratio != nil {
+		t.Errorf("rules[1] = %+v", rules[1])
+	}
+	if config.Sampler.Default.Ratio == nil || *config.Sampler.Default.Ratio != 0.1 {
+		t.Errorf("default = %+v, want ratio 0.1", config.Sampler.Default)
+	}
+}
+
+func TestSetSamplerConfig(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	sampler := newReloadableSampler(sdktrace.AlwaysSample())
+	tracer := newOTELTracer(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sampler)), nil, "test")
+	tracer.sampler = sampler
+	defer tracer.Close()
+
+	start := func(name string) {
+		span, _ := StartSpanWithKind(tracer, context.Background(), name, SpanKindServer)
+		span.Finish()
+	}
+
+	start("GET /health")
+	err := tracer.SetSamplerConfig(SamplerConfig{
+		Type:  "rule_based",
+		Rules: []SamplingRule{{SpanName: "GET /health", Ratio: ratio(0)}},
+	})
+	if err != nil {
+		t.Fatalf("SetSamplerConfig() error = %v", err)
+	}
+	start("GET /health")
+	start("GET /orders")
+
+	// 无效配置不会替换当前采样器
+	if err := tracer.SetSamplerConfig(SamplerConfig{Type: "unknown"}); err == nil {
+		t.Error("SetSamplerConfig() should reject invalid config")
+	}
+	start("GET /health")
+
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		names = append(names, span.Name)
+	}
+	if len(names) != 2 || names[0] != "GET /health" || names[1] != "GET /orders" {
+		t.Errorf("exported spans = %v, want [GET /health GET /orders]", names)
+	}
+
+	// NewOTELTracer 使用外部的 TracerProvider，不能更新采样配置
+	external := NewOTELTracer(sdktrace.NewTracerProvider(), "external")
+	defer external.Close()
+	if err := external.SetSamplerConfig(SamplerConfig{Type: "never"}); err == nil {
+		t.Error("SetSamplerConfig() should fail for tracers not created by InitOpenTelemetryTracer")
+	}
+}
+
+func TestReloadSampler(t *testing.T) {
+	original := GetTracer()
+	defer RegisterTracer(original)
+
+	RegisterTracer(&mockTracer{})
+	if err := ReloadSampler(); err == nil {
+		t.Error("ReloadSampler() should fail when the tracer is not an OTELTracer")
+	}
+}
+
+func TestReloadSamplerKeepsCurrentSamplerOnError(t *testing.T) {
+	sampler := newReloadableSampler(sdktrace.AlwaysSample())
+	tracer := newOTELTracer(sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler)), nil, "test")
+	tracer.sampler = sampler
+	defer tracer.Close()
+
+	configDir := t.TempDir()
+	configPath := filepath.Join(configDir, "trace.yaml")
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	loader.SetEnv("")
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("sampler:\n  type: traceid_ratio\n  ratio: 0.5\n")
+	if err := tracer.reloadSampler(loader); err != nil {
+		t.Fatalf("reloadSampler() error = %v", err)
+	}
+	want := sampler.Description()
+	if want == sdktrace.AlwaysSample().Description() {
+		t.Fatalf("sampler was not updated: %s", want)
+	}
+
+	// 配置无效或配置文件被删除时保留当前的采样器
+	write("sampler:\n  type: traceid_ratio\n  ratio: 1.5\n")
+	if err := tracer.reloadSampler(loader); err == nil {
+		t.Error("reloadSampler() should fail for an invalid config")
+	}
+	write("sampler:\n  type: bogus\n")
+	if err := tracer.reloadSampler(loader); err == nil {
+		t.Error("reloadSampler() should fail for an unknown sampler type")
+	}
+	if err := os.Remove(configPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracer.reloadSampler(loader); err == nil {
+		t.Error("reloadSampler() should fail when no config file is found")
+	}
+
+	if got := sampler.Description(); got != want {
+		t.Errorf("sampler = %s, want %s", got, want)
+	}
+}
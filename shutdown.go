@@ -0,0 +1,123 @@
+package zltrace
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/zlxdbj/zllog"
+)
+
+// ============================================================================
+// 生命周期管理
+// ============================================================================
+
+// Shutdowner 可以在 deadline 内优雅关闭的 Tracer（可选接口）
+// OTELTracer 实现了此接口
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Flusher 可以立即导出缓冲 span 的 Tracer（可选接口）
+// OTELTracer 实现了此接口
+type Flusher interface {
+	ForceFlush(ctx context.Context) error
+}
+
+// Shutdown 关闭全局 tracer，导出所有缓冲的 span
+//
+// 如果全局 tracer 实现了 Shutdowner，则在 ctx 的 deadline 内完成关闭；
+// 否则退化为调用 Close()。没有注册 tracer 时直接返回 nil。
+//
+// 使用示例：
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	if err := zltrace.Shutdown(ctx); err != nil {
+//	    zllog.Error(ctx, "shutdown", "关闭追踪系统失败", err)
+//	}
+func Shutdown(ctx context.Context) error {
+	tracer := GetTracer()
+	if tracer == nil {
+		return nil
+	}
+	if s, ok := tracer.(Shutdowner); ok {
+		return s.Shutdown(ctx)
+	}
+	return tracer.Close()
+}
+
+// ForceFlush 立即导出全局 tracer 中缓冲的 span
+//
+// 全局 tracer 未实现 Flusher 时什么也不做。
+func ForceFlush(ctx context.Context) error {
+	tracer := GetTracer()
+	if tracer == nil {
+		return nil
+	}
+	if f, ok := tracer.(Flusher); ok {
+		return f.ForceFlush(ctx)
+	}
+	return nil
+}
+
+// ShutdownOnSignal 收到退出信号时刷新并关闭全局 tracer
+//
+// 默认监听 SIGTERM 和 SIGINT，Kubernetes 在删除 Pod 时会先发送 SIGTERM，
+// 这样可以在进程被杀死之前把批处理器中缓冲的 span 导出。
+// timeout 为关闭时最多等待的时间（<=0 时使用 5 秒）。
+//
+// 关闭完成后会停止监听并把信号重新发给自身，
+// 没有其他信号处理器时进程按默认行为退出；业务自己也监听了该信号时，会再收到一次。
+//
+// 返回的 stop 函数用于取消监听（例如业务自己负责调用 Shutdown 时）。
+//
+// 使用示例：
+//
+//	if err := zltrace.InitTracer(); err != nil { ... }
+//	stop := zltrace.ShutdownOnSignal(5 * time.Second)
+//	defer stop()
+func ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) (stop func()) {
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigCh, signals...)
+
+	go func() {
+		select {
+		case sig := <-sigCh:
+			signal.Stop(sigCh)
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			if err := Shutdown(ctx); err != nil {
+				zllog.Error(ctx, "trace", "收到退出信号，关闭追踪系统失败", err,
+					zllog.String("signal", sig.String()))
+			} else {
+				zllog.Info(ctx, "trace", "收到退出信号，追踪系统已关闭",
+					zllog.String("signal", sig.String()))
+			}
+			cancel()
+
+			// 恢复默认行为：把信号重新发给自身
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Signal(sig)
+			}
+		case <-done:
+			signal.Stop(sigCh)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
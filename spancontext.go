@@ -0,0 +1,154 @@
+package zltrace
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ============================================================================
+// SpanContext - 可移植的 span 上下文
+// ============================================================================
+
+// SpanContext 可移植的 span 上下文（值类型）
+//
+// 用于记录 span_id、判断采样状态，或在没有 Carrier 的场景下手动传播 trace
+// （例如写入数据库任务表、跨语言的自定义协议）。
+// 字符串形式为 W3C traceparent：00-{trace_id}-{span_id}-{flags}
+type SpanContext struct {
+	// TraceID 32 位十六进制 trace_id
+	TraceID string
+	// SpanID 16 位十六进制 span_id
+	SpanID string
+	// Sampled 是否被采样（traceparent flags 的最低位）
+	Sampled bool
+	// Remote 是否来自上游进程
+	Remote bool
+	// TraceState W3C tracestate（可选，不包含在 String() 中；格式无效时被忽略）
+	TraceState string
+}
+
+// IsValid trace_id 和 span_id 是否都合法且非零（不检查 TraceState）
+func (sc SpanContext) IsValid() bool {
+	_, err := sc.toOTEL()
+	return err == nil
+}
+
+// String 返回 W3C traceparent 格式的字符串
+// SpanContext 无效时返回空字符串
+func (sc SpanContext) String() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID + "-" + sc.SpanID + "-" + flags
+}
+
+// ParseSpanContext 解析 W3C traceparent 格式的字符串
+//
+// 解析结果的 Remote 为 true，可以通过 ContextWithRemoteSpanContext 继续该 trace。
+//
+// 使用示例：
+//
+//	sc, err := zltrace.ParseSpanContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+func ParseSpanContext(traceparent string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("无效的 traceparent: %q", traceparent)
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || version == "ff" || !isLowerHex(version) {
+		return SpanContext{}, fmt.Errorf("无效的 traceparent 版本: %q", version)
+	}
+	// 版本 00 必须恰好 4 段；更高版本允许追加字段
+	if version == "00" && len(parts) != 4 {
+		return SpanContext{}, fmt.Errorf("无效的 traceparent: %q", traceparent)
+	}
+	flagBits, err := strconv.ParseUint(flags, 16, 8)
+	if len(flags) != 2 || !isLowerHex(flags) || err != nil {
+		return SpanContext{}, fmt.Errorf("无效的 traceparent flags: %q", flags)
+	}
+
+	sc := SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: flagBits&uint64(trace.FlagsSampled) != 0,
+		Remote:  true,
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("无效的 traceparent trace_id/span_id: %q", traceparent)
+	}
+	return sc, nil
+}
+
+// ContextWithRemoteSpanContext 将上游的 SpanContext 放入 context
+//
+// 之后通过 OTELTracer 创建的 span 会作为它的子 span，继续同一个 trace。
+// SpanContext 无效时原样返回 ctx。
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	otelSC, err := sc.toOTEL()
+	if err != nil {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, otelSC)
+}
+
+// toOTEL 转换为 OTel 的 SpanContext
+//
+// 按 W3C 规范，tracestate 无效时丢弃 tracestate，保留 traceparent。
+func (sc SpanContext) toOTEL() (trace.SpanContext, error) {
+	traceID, err := trace.TraceIDFromHex(sc.TraceID)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	spanID, err := trace.SpanIDFromHex(sc.SpanID)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+
+	config := trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Remote:  sc.Remote,
+	}
+	if sc.Sampled {
+		config.TraceFlags = trace.FlagsSampled
+	}
+	if sc.TraceState != "" {
+		if ts, err := trace.ParseTraceState(sc.TraceState); err == nil {
+			config.TraceState = ts
+		}
+	}
+	return trace.NewSpanContext(config), nil
+}
+
+// spanContextFromOTEL 从 OTel 的 SpanContext 转换
+func spanContextFromOTEL(sc trace.SpanContext) SpanContext {
+	if !sc.IsValid() {
+		return SpanContext{}
+	}
+	return SpanContext{
+		TraceID:    sc.TraceID().String(),
+		SpanID:     sc.SpanID().String(),
+		Sampled:    sc.IsSampled(),
+		Remote:     sc.IsRemote(),
+		TraceState: sc.TraceState().String(),
+	}
+}
+
+// isLowerHex 是否只包含小写十六进制字符（W3C 规范要求小写）
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,452 @@
+package zltrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zlxdbj/zllog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ============================================================================
+// SpoolExporter - 导出失败时缓存到磁盘，恢复后重放
+// ============================================================================
+
+const (
+	spoolSegmentExt = ".seg"
+	spoolTempExt    = ".tmp"
+
+	// defaultReplayInterval 重放检查间隔，replay_rate 按此间隔分配
+	defaultReplayInterval = time.Second
+
+	// maxReplayRejects 主 Exporter 可用时同一个 segment 最多重放失败的次数，超过后丢弃
+	maxReplayRejects = 3
+)
+
+// SpoolExporter 主 Exporter 导出失败时把 span 写入本地磁盘队列，恢复后按速率重放
+//
+// 用于追踪系统短时间不可用（故障、发布）的场景，避免 trace 出现空洞：
+//   - 每个失败的批次写成一个 segment 文件（OTLP/JSON，每行一个 span，格式与 FileExporter 相同）
+//   - segment 先写临时文件并 fsync，再原子重命名；进程崩溃只会留下临时文件，启动时清理
+//   - 后台每秒按 replay_rate 从最旧的 segment 开始重放，部分重放的 segment 只保留剩余的 span
+//   - 超过 max_bytes 时删除最旧的 segment，超过 max_age 的 segment 直接丢弃
+//   - 主 Exporter 可用（其他数据导出成功）但某个 segment 连续 3 次重放失败时丢弃它，
+//     避免一个被 collector 拒绝的 segment（数据无效、批次过大等）阻塞后面的重放
+//
+// 关闭时未重放的 segment 保留在磁盘上，下次启动后继续重放。
+type SpoolExporter struct {
+	primary        sdktrace.SpanExporter
+	config         SpoolConfig
+	replayInterval time.Duration
+
+	mu       sync.Mutex // 保护 segment 文件的写入、改写和删除
+	seq      uint64
+	spooling bool           // 是否有待重放的数据（用于输出状态切换日志）
+	rejects  map[string]int // 主 Exporter 可用时各 segment 重放失败的次数
+
+	healthy atomic.Bool // 上一轮重放之后是否有直接导出成功（说明主 Exporter 可用）
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// spoolSegment 磁盘队列中的一个 segment 文件
+type spoolSegment struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// NewSpoolExporter 创建磁盘缓冲 Exporter，并启动后台重放
+func NewSpoolExporter(primary sdktrace.SpanExporter, config SpoolConfig) (*SpoolExporter, error) {
+	return newSpoolExporter(primary, config, defaultReplayInterval)
+}
+
+func newSpoolExporter(primary sdktrace.SpanExporter, config SpoolConfig, replayInterval time.Duration) (*SpoolExporter, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("磁盘队列目录不能为空")
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建磁盘队列目录失败: %w", err)
+	}
+
+	e := &SpoolExporter{
+		primary:        primary,
+		config:         config,
+		replayInterval: replayInterval,
+		rejects:        make(map[string]int),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	if err := e.removeTempFiles(); err != nil {
+		return nil, err
+	}
+	if segments, _ := e.segments(); len(segments) > 0 {
+		e.spooling = true
+		zllog.Info(context.Background(), "trace.exporter", "磁盘队列中有待重放的数据",
+			zllog.Int("segments", len(segments)))
+	}
+
+	go e.replayLoop()
+	return e, nil
+}
+
+// ExportSpans 导出 span，失败时写入磁盘队列（实现 SpanExporter 接口）
+//
+// 写入磁盘队列成功后返回 nil，数据会在主 Exporter 恢复后重放。
+func (e *SpoolExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.primary.ExportSpans(ctx, spans)
+	if err == nil {
+		e.healthy.Store(true)
+		return nil
+	}
+	if spoolErr := e.spool(spans); spoolErr != nil {
+		return fmt.Errorf("导出失败且写入磁盘队列失败: %w", errors.Join(err, spoolErr))
+	}
+	return nil
+}
+
+// ForceFlush 刷新主 Exporter（磁盘队列只由后台重放）
+func (e *SpoolExporter) ForceFlush(ctx context.Context) error {
+	if flusher, ok := e.primary.(interface {
+		ForceFlush(ctx context.Context) error
+	}); ok {
+		return flusher.ForceFlush(ctx)
+	}
+	return nil
+}
+
+// Shutdown 停止后台重放并关闭主 Exporter
+//
+// 未重放的数据保留在磁盘上，下次启动后继续重放。ctx 在重放结束前到期时仍会关闭主 Exporter，
+// 并返回 ctx 的错误。
+func (e *SpoolExporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stop) })
+	var err error
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return errors.Join(err, e.primary.Shutdown(ctx))
+}
+
+// spool 将一批 span 写成新的 segment
+func (e *SpoolExporter) spool(spans []sdktrace.ReadOnlySpan) error {
+	data, err := encodeSpanLines(spans)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if int64(len(data)) > e.config.MaxBytes {
+		return fmt.Errorf("批次大小 %d 字节超过磁盘队列上限 %d 字节", len(data), e.config.MaxBytes)
+	}
+	if err := e.enforceLimits(int64(len(data))); err != nil {
+		return err
+	}
+
+	e.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), e.seq%1000000, spoolSegmentExt)
+	if err := writeFileAtomic(filepath.Join(e.config.Dir, name), data); err != nil {
+		return err
+	}
+
+	if !e.spooling {
+		e.spooling = true
+		zllog.Warn(context.Background(), "trace.exporter", "导出失败，span 写入磁盘队列等待重放",
+			zllog.String("dir", e.config.Dir))
+	}
+	return nil
+}
+
+// enforceLimits 删除过期的 segment，并删除最旧的 segment 直到能容纳 incoming 字节（调用方持有锁）
+func (e *SpoolExporter) enforceLimits(incoming int64) error {
+	segments, err := e.segments()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	kept := segments[:0]
+	for _, seg := range segments {
+		if e.config.MaxAge > 0 && time.Since(seg.modTime) > e.config.MaxAge {
+			e.drop(seg, "超过 max_age")
+			continue
+		}
+		total += seg.size
+		kept = append(kept, seg)
+	}
+
+	for len(kept) > 0 && total+incoming > e.config.MaxBytes {
+		e.drop(kept[0], "超过 max_bytes")
+		total -= kept[0].size
+		kept = kept[1:]
+	}
+	return nil
+}
+
+// drop 删除 segment 并输出日志（调用方持有锁）
+func (e *SpoolExporter) drop(seg spoolSegment, reason string) {
+	delete(e.rejects, seg.path)
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		zllog.Error(context.Background(), "trace.exporter", "删除磁盘队列 segment 失败", err,
+			zllog.String("segment", filepath.Base(seg.path)))
+		return
+	}
+	zllog.Warn(context.Background(), "trace.exporter", "丢弃磁盘队列中的 span",
+		zllog.String("segment", filepath.Base(seg.path)),
+		zllog.String("reason", reason))
+}
+
+// segments 按时间顺序（从旧到新）列出所有 segment
+func (e *SpoolExporter) segments() ([]spoolSegment, error) {
+	entries, err := os.ReadDir(e.config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取磁盘队列目录失败: %w", err)
+	}
+
+	var segments []spoolSegment
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spoolSegmentExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, spoolSegment{
+			path:    filepath.Join(e.config.Dir, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].path < segments[j].path })
+	return segments, nil
+}
+
+// removeTempFiles 删除上次崩溃时未写完的临时文件
+func (e *SpoolExporter) removeTempFiles() error {
+	matches, err := filepath.Glob(filepath.Join(e.config.Dir, "*"+spoolTempExt))
+	if err != nil {
+		return fmt.Errorf("清理磁盘队列临时文件失败: %w", err)
+	}
+	for _, path := range matches {
+		os.Remove(path)
+	}
+	return nil
+}
+
+// replayLoop 后台定期重放磁盘队列
+func (e *SpoolExporter) replayLoop() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.replayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			e.replay()
+		}
+	}
+}
+
+// replay 从最旧的 segment 开始重放，每次最多重放 replay_rate * 间隔 个 span
+//
+// 无法读取的 segment（已被删除，或文件损坏且删除失败）本轮跳过，不会反复重试同一个文件。
+// 重放失败的 segment 本轮也跳过，继续尝试后面的 segment；本轮没有任何成功时，
+// 连续失败 maxReplayRejects 次后停止，认为主 Exporter 仍不可用，下一轮再试。
+// 主 Exporter 可用（本轮有成功的重放，或期间有直接导出成功）时，失败的 segment 记一次拒绝，
+// 累计 maxReplayRejects 次后丢弃。
+func (e *SpoolExporter) replay() {
+	budget := int(float64(e.config.ReplayRate) * e.replayInterval.Seconds())
+	if budget < 1 {
+		budget = 1
+	}
+
+	skipped := make(map[string]bool)
+	var failed []spoolSegment
+	succeeded := false
+	defer func() {
+		if succeeded || e.healthy.Swap(false) {
+			e.reject(failed)
+		}
+	}()
+
+	for budget > 0 {
+		seg, ok := e.oldestSegment(skipped)
+		if !ok {
+			return
+		}
+
+		stubs, err := ReadSpanFile(seg.path)
+		if err != nil {
+			skipped[seg.path] = true
+			if !errors.Is(err, os.ErrNotExist) {
+				e.mu.Lock()
+				e.drop(seg, "文件损坏")
+				e.mu.Unlock()
+			}
+			continue
+		}
+
+		n := len(stubs)
+		if n > budget {
+			n = budget
+		}
+		if n > 0 {
+			if err := e.primary.ExportSpans(context.Background(), stubs[:n].Snapshots()); err != nil {
+				skipped[seg.path] = true
+				failed = append(failed, seg)
+				if !succeeded && len(failed) >= maxReplayRejects {
+					return
+				}
+				continue
+			}
+			succeeded = true
+		}
+		budget -= n
+
+		if err := e.complete(seg, stubs[n:].Snapshots()); err != nil {
+			zllog.Error(context.Background(), "trace.exporter", "更新磁盘队列 segment 失败", err,
+				zllog.String("segment", filepath.Base(seg.path)))
+			return
+		}
+	}
+}
+
+// reject 主 Exporter 可用时记录 segment 被拒绝，累计 maxReplayRejects 次后丢弃
+func (e *SpoolExporter) reject(segments []spoolSegment) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, seg := range segments {
+		e.rejects[seg.path]++
+		if e.rejects[seg.path] >= maxReplayRejects {
+			e.drop(seg, "主 Exporter 多次拒绝")
+		}
+	}
+}
+
+// oldestSegment 返回不在 skipped 中的最旧的 segment；队列为空时输出重放完成日志
+func (e *SpoolExporter) oldestSegment(skipped map[string]bool) (spoolSegment, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.enforceLimits(0); err != nil {
+		return spoolSegment{}, false
+	}
+	segments, err := e.segments()
+	if err != nil {
+		return spoolSegment{}, false
+	}
+	for _, seg := range segments {
+		if !skipped[seg.path] {
+			return seg, true
+		}
+	}
+	if len(segments) == 0 && e.spooling {
+		e.spooling = false
+		zllog.Info(context.Background(), "trace.exporter", "磁盘队列已全部重放")
+	}
+	return spoolSegment{}, false
+}
+
+// complete 删除已重放完的 segment，或改写为只包含剩余的 span
+func (e *SpoolExporter) complete(seg spoolSegment, remaining []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.rejects, seg.path)
+
+	// 重放期间 segment 可能已被 enforceLimits 删除
+	if _, err := os.Stat(seg.path); os.IsNotExist(err) {
+		return nil
+	}
+	if len(remaining) == 0 {
+		if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := encodeSpanLines(remaining)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(seg.path, data); err != nil {
+		return err
+	}
+	// 保留原始时间，max_age 从第一次写入开始计算
+	return os.Chtimes(seg.path, seg.modTime, seg.modTime)
+}
+
+// encodeSpanLines 将 span 编码为 OTLP/JSON lines
+func encodeSpanLines(spans []sdktrace.ReadOnlySpan) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, span := range spans {
+		line, err := json.Marshal(newJSONTracesData(span))
+		if err != nil {
+			return nil, fmt.Errorf("序列化 span 失败: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFileAtomic 先写临时文件并 fsync，再重命名为目标文件，最后 fsync 目录使重命名持久化
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + spoolTempExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("创建磁盘队列文件失败: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("写入磁盘队列文件失败: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("写入磁盘队列文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入磁盘队列文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入磁盘队列文件失败: %w", err)
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("写入磁盘队列文件失败: %w", err)
+	}
+	return nil
+}
+
+// syncDir fsync 目录，确保目录项的变化（如重命名）在掉电后不会丢失
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,368 @@
+package zltrace
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testSpans 生成 n 个已结束的 span
+func testSpans(n int) []sdktrace.ReadOnlySpan {
+	stubs := make(tracetest.SpanStubs, n)
+	now := time.Now()
+	tp := sdktrace.NewTracerProvider()
+	for i := range stubs {
+		_, span := tp.Tracer("test").Start(context.Background(), "op")
+		stubs[i] = tracetest.SpanStub{
+			Name:        "op",
+			SpanContext: span.SpanContext(),
+			StartTime:   now,
+			EndTime:     now.Add(time.Millisecond),
+			Attributes:  []attribute.KeyValue{attribute.Int("index", i)},
+		}
+	}
+	return stubs.Snapshots()
+}
+
+// spoolSegments 返回磁盘队列目录中的 segment 文件名
+func spoolSegments(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+// newStoppedSpoolExporter 创建不在后台重放的 SpoolExporter，由测试手动调用 replay
+func newStoppedSpoolExporter(t *testing.T, primary sdktrace.SpanExporter, config SpoolConfig) *SpoolExporter {
+	t.Helper()
+	exporter, err := newSpoolExporter(primary, config, time.Second)
+	if err != nil {
+		t.Fatalf("newSpoolExporter() error = %v", err)
+	}
+	exporter.stopOnce.Do(func() { close(exporter.stop) })
+	<-exporter.done
+	return exporter
+}
+
+func TestSpoolExporterReplaysAfterCollectorOutage(t *testing.T) {
+	endpoint, collector := startCollector(t)
+	collector.setDown(true)
+
+	primary, err := createOTLPExporter(&TraceConfig{Exporter: ExporterConfig{
+		OTLP: OTLPConfig{Endpoint: endpoint, Insecure: true, Timeout: 5},
+	}})
+	if err != nil {
+		t.Fatalf("createOTLPExporter() error = %v", err)
+	}
+	dir := t.TempDir()
+	exporter, err := newSpoolExporter(primary, SpoolConfig{Dir: dir, MaxBytes: 1 << 20, ReplayRate: 1000}, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("newSpoolExporter() error = %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	// collector 故障期间导出的数据写入磁盘队列
+	for i := 0; i < 3; i++ {
+		if err := exporter.ExportSpans(context.Background(), testSpans(2)); err != nil {
+			t.Fatalf("ExportSpans() during outage error = %v", err)
+		}
+	}
+	if n := len(spoolSegments(t, dir)); n == 0 {
+		t.Fatal("failed batches should be spooled to disk")
+	}
+
+	// collector 恢复后全部重放
+	collector.setDown(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for (collector.count() < 6 || len(spoolSegments(t, dir)) > 0) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if collector.count() != 6 {
+		t.Errorf("collector received %d spans after recovery, want 6", collector.count())
+	}
+	if n := len(spoolSegments(t, dir)); n != 0 {
+		t.Errorf("%d segments left after replay", n)
+	}
+}
+
+func TestSpoolExporterReplayRate(t *testing.T) {
+	primary := &switchableExporter{down: true}
+	dir := t.TempDir()
+	exporter := newStoppedSpoolExporter(t, primary, SpoolConfig{Dir: dir, MaxBytes: 1 << 20, ReplayRate: 4})
+
+	exporter.ExportSpans(context.Background(), testSpans(10))
+	exporter.replay()
+	if primary.exported != 0 || len(spoolSegments(t, dir)) != 1 {
+		t.Fatalf("replay while down: exported = %d, segments = %d", primary.exported, len(spoolSegments(t, dir)))
+	}
+
+	primary.setDown(false)
+	for _, want := range []int{4, 8, 10} {
+		exporter.replay()
+		if primary.exported != want {
+			t.Fatalf("exported = %d, want %d", primary.exported, want)
+		}
+	}
+	if n := len(spoolSegments(t, dir)); n != 0 {
+		t.Errorf("%d segments left after replay", n)
+	}
+
+	// 部分重放的 segment 只保留剩余的 span，不会重复发送
+	exporter.ExportSpans(context.Background(), testSpans(10)) // 主 Exporter 正常，直接导出
+	if primary.exported != 20 || len(spoolSegments(t, dir)) != 0 {
+		t.Errorf("healthy export should bypass the spool: exported = %d", primary.exported)
+	}
+}
+
+func TestSpoolExporterNonFiniteFloat(t *testing.T) {
+	primary := &switchableExporter{down: true}
+	exporter := newStoppedSpoolExporter(t, primary, SpoolConfig{Dir: t.TempDir(), MaxBytes: 1 << 20, ReplayRate: 100})
+
+	// NaN 属性不影响整批数据写入磁盘
+	stubs := tracetest.SpanStubsFromReadOnlySpans(testSpans(2))
+	stubs[0].Attributes = append(stubs[0].Attributes, attribute.Float64("ratio", math.NaN()))
+	if err := exporter.ExportSpans(context.Background(), stubs.Snapshots()); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+
+	primary.setDown(false)
+	exporter.replay()
+	if primary.exported != 2 {
+		t.Errorf("replayed %d spans, want 2", primary.exported)
+	}
+}
+
+func TestSpoolExporterLimits(t *testing.T) {
+	primary := &switchableExporter{down: true}
+	dir := t.TempDir()
+
+	batch, _ := encodeSpanLines(testSpans(1))
+	maxBytes := int64(len(batch))*2 + 10
+	exporter := newStoppedSpoolExporter(t, primary, SpoolConfig{Dir: dir, MaxBytes: maxBytes, MaxAge: time.Hour, ReplayRate: 100})
+
+	for i := 0; i < 5; i++ {
+		if err := exporter.ExportSpans(context.Background(), testSpans(1)); err != nil {
+			t.Fatalf("ExportSpans() error = %v", err)
+		}
+	}
+	segments := spoolSegments(t, dir)
+	if len(segments) != 2 {
+		t.Fatalf("segments = %d, want 2 (oldest dropped by max_bytes)", len(segments))
+	}
+
+	// 超过 max_bytes 的单个批次直接返回错误
+	if err := exporter.ExportSpans(context.Background(), testSpans(5)); err == nil {
+		t.Error("batch larger than max_bytes should fail")
+	}
+
+	// 超过 max_age 的 segment 被丢弃
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(segments[0], old, old)
+	exporter.replay()
+	if remaining := spoolSegments(t, dir); len(remaining) != 1 || remaining[0] != segments[1] {
+		t.Errorf("segments after max_age = %v, want [%s]", remaining, segments[1])
+	}
+}
+
+func TestSpoolExporterRecoversAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	config := SpoolConfig{Dir: dir, MaxBytes: 1 << 20, ReplayRate: 100}
+
+	// 第一个进程：collector 不可用，数据留在磁盘上
+	first := newStoppedSpoolExporter(t, &switchableExporter{down: true}, config)
+	first.ExportSpans(context.Background(), testSpans(3))
+	first.Shutdown(context.Background())
+
+	// 模拟写到一半崩溃留下的临时文件
+	partial := filepath.Join(dir, "99999999999999999999-000001"+spoolSegmentExt+spoolTempExt)
+	if err := os.WriteFile(partial, []byte(`{"resourceSpans":[`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二个进程：启动时清理临时文件，并重放上次留下的数据
+	primary := &switchableExporter{}
+	second := newStoppedSpoolExporter(t, primary, config)
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Error("partial temp file should be removed on startup")
+	}
+	second.replay()
+	if primary.exported != 3 {
+		t.Errorf("replayed %d spans after restart, want 3", primary.exported)
+	}
+}
+
+func TestSpoolExporterSkipsUnreadableSegment(t *testing.T) {
+	primary := &switchableExporter{down: true}
+	dir := t.TempDir()
+	exporter := newStoppedSpoolExporter(t, primary, SpoolConfig{Dir: dir, MaxBytes: 1 << 20, ReplayRate: 100})
+
+	// 最旧的 segment 无法读取（指向不存在的文件），每次读取都返回 ErrNotExist
+	dangling := filepath.Join(dir, "00000000000000000000-000000"+spoolSegmentExt)
+	if err := os.Symlink(filepath.Join(dir, "missing"), dangling); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+	exporter.ExportSpans(context.Background(), testSpans(3))
+	primary.setDown(false)
+
+	done := make(chan struct{})
+	go func() {
+		exporter.replay()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay should not spin on an unreadable segment")
+	}
+	if primary.exported != 3 {
+		t.Errorf("replayed %d spans, want 3 from the segment after the unreadable one", primary.exported)
+	}
+}
+
+// poisonExporter 拒绝包含名为 poison 的 span 的批次，模拟 collector 拒绝某些数据
+type poisonExporter struct {
+	switchableExporter
+}
+
+func (e *poisonExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	for _, span := range spans {
+		if span.Name() == "poison" {
+			return errors.New("invalid argument")
+		}
+	}
+	return e.switchableExporter.ExportSpans(ctx, spans)
+}
+
+func TestSpoolExporterDropsRejectedSegment(t *testing.T) {
+	primary := &poisonExporter{switchableExporter{down: true}}
+	dir := t.TempDir()
+	exporter := newStoppedSpoolExporter(t, primary, SpoolConfig{Dir: dir, MaxBytes: 1 << 20, ReplayRate: 100})
+
+	poison := tracetest.SpanStubsFromReadOnlySpans(testSpans(1))
+	poison[0].Name = "poison"
+	exporter.ExportSpans(context.Background(), poison.Snapshots())
+	exporter.ExportSpans(context.Background(), testSpans(2))
+
+	// collector 不可用：多轮重放失败也不丢弃数据
+	for i := 0; i < 2*maxReplayRejects; i++ {
+		exporter.replay()
+	}
+	if n := len(spoolSegments(t, dir)); n != 2 {
+		t.Fatalf("segments = %d during outage, want 2", n)
+	}
+
+	// collector 恢复但拒绝最旧的 segment：后面的 segment 继续重放
+	primary.setDown(false)
+	exporter.replay()
+	if primary.exported != 2 {
+		t.Errorf("exported = %d, want 2 from the segment after the rejected one", primary.exported)
+	}
+	// 只剩被拒绝的 segment 时，由直接导出成功确认 collector 可用
+	for i := 1; i < maxReplayRejects; i++ {
+		exporter.ExportSpans(context.Background(), testSpans(1))
+		exporter.replay()
+	}
+	if n := len(spoolSegments(t, dir)); n != 0 {
+		t.Errorf("segments = %d, rejected segment should be dropped after %d attempts", n, maxReplayRejects)
+	}
+}
+
+func TestSpoolExporterShutdownTimeout(t *testing.T) {
+	dir := t.TempDir()
+	data, _ := encodeSpanLines(testSpans(1))
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001-000001"+spoolSegmentExt), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	primary := &blockingExporter{release: make(chan struct{})}
+	defer close(primary.release)
+	exporter, err := newSpoolExporter(primary, SpoolConfig{Dir: dir, MaxBytes: 1 << 20, ReplayRate: 100}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("newSpoolExporter() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // 等待后台重放阻塞在主 Exporter 上
+
+	// 重放没有结束，ctx 到期后仍然关闭主 Exporter
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := exporter.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+	if !primary.shutdown.Load() {
+		t.Error("primary exporter should be shut down even when ctx expires")
+	}
+}
+
+func TestConfigLoaderSpool(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+exporter:
+  type: otlp
+  otlp:
+    endpoint: collector:4317
+  spool:
+    enabled: true
+    dir: /var/lib/myapp/trace-spool
+    max_bytes: 1048576
+    max_age: 2h
+    replay_rate: 500
+`
+	if err := os.WriteFile(filepath.Join(configDir, "trace.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader()
+	loader.SetConfigDirs(configDir)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := SpoolConfig{Enabled: true, Dir: "/var/lib/myapp/trace-spool", MaxBytes: 1048576, MaxAge: 2 * time.Hour, ReplayRate: 500}
+	if config.Exporter.Spool != want {
+		t.Errorf("spool = %+v, want %+v", config.Exporter.Spool, want)
+	}
+
+	tests := []struct {
+		name    string
+		spool   SpoolConfig
+		wantErr string
+	}{
+		{"no dir", SpoolConfig{Enabled: true, MaxBytes: 1, ReplayRate: 1}, "dir"},
+		{"no max bytes", SpoolConfig{Enabled: true, Dir: "x", ReplayRate: 1}, "max_bytes"},
+		{"no replay rate", SpoolConfig{Enabled: true, Dir: "x", MaxBytes: 1}, "replay_rate"},
+		{"max age without unit", SpoolConfig{Enabled: true, Dir: "x", MaxBytes: 1, ReplayRate: 1, MaxAge: 24}, "max_age"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &TraceConfig{Enabled: true, Exporter: ExporterConfig{
+				Type:  "otlp",
+				OTLP:  OTLPConfig{Endpoint: "localhost:4317", Insecure: true},
+				Spool: tt.spool,
+			}}
+			if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateConfig() error = %v, want error about %s", err, tt.wantErr)
+			}
+		})
+	}
+
+	withFailover := &TraceConfig{Enabled: true, Exporter: ExporterConfig{
+		Type:     "otlp",
+		OTLP:     OTLPConfig{Endpoint: "localhost:4317", Insecure: true},
+		Spool:    SpoolConfig{Enabled: true, Dir: "x", MaxBytes: 1, ReplayRate: 1},
+		Failover: FailoverConfig{Enabled: true, FailureThreshold: 1, ProbeInterval: time.Second},
+	}}
+	if err := validateConfig(withFailover); err == nil {
+		t.Error("spool and failover should not be enabled together")
+	}
+}
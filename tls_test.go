@@ -0,0 +1,301 @@
+package zltrace
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testCerts 测试用的 CA、服务端证书和客户端证书（PEM 文件路径）
+type testCerts struct {
+	caFile, serverCert, serverKey, clientCert, clientKey string
+	caPool                                               *x509.CertPool
+}
+
+// newTestCerts 在临时目录生成一套自签名证书
+func newTestCerts(t *testing.T) *testCerts {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "zltrace test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{"localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		certFile := writePEM(t, dir, name+".pem", "CERTIFICATE", der)
+		keyFile := writePEM(t, dir, name+".key", "EC PRIVATE KEY", keyDER)
+		return certFile, keyFile
+	}
+
+	certs := &testCerts{caFile: writePEM(t, dir, "ca.pem", "CERTIFICATE", caDER), caPool: x509.NewCertPool()}
+	certs.caPool.AddCert(caCert)
+	certs.serverCert, certs.serverKey = issue("server", 2, x509.ExtKeyUsageServerAuth)
+	certs.clientCert, certs.clientKey = issue("client", 3, x509.ExtKeyUsageClientAuth)
+	return certs
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testCollector 本地的 OTLP gRPC collector 替身，记录收到的 span 数量
+type testCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mu        sync.Mutex
+	spans     int
+	metadata  metadata.MD
+	attempts  int
+	failFirst int           // 前 failFirst 次请求返回 Unavailable
+	down      bool          // 模拟 collector 故障，所有请求返回 Unavailable
+	delay     time.Duration // 每次请求先等待 delay，模拟响应慢的 collector
+}
+
+func (c *testCollector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	if c.delay > 0 {
+		select {
+		case <-time.After(c.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts++
+	if c.attempts <= c.failFirst || c.down {
+		return nil, status.Error(grpccodes.Unavailable, "collector unavailable")
+	}
+	c.metadata, _ = metadata.FromIncomingContext(ctx)
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans += len(ss.Spans)
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *testCollector) setDown(down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = down
+}
+
+func (c *testCollector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spans
+}
+
+// startTLSCollector 启动要求客户端证书的 TLS collector，返回监听地址
+func startTLSCollector(t *testing.T, certs *testCerts) (string, *testCollector) {
+	t.Helper()
+
+	serverCert, err := tls.LoadX509KeyPair(certs.serverCert, certs.serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    certs.caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+
+	return startCollector(t, grpc.Creds(creds))
+}
+
+// startCollector 启动本地 gRPC collector，返回监听地址
+func startCollector(t *testing.T, opts ...grpc.ServerOption) (string, *testCollector) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := &testCollector{}
+	server := grpc.NewServer(opts...)
+	coltracepb.RegisterTraceServiceServer(server, collector)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String(), collector
+}
+
+// exportOneSpan 通过 exporter 同步导出一个 span，最多等待 timeout
+func exportOneSpan(exporter sdktrace.SpanExporter, timeout time.Duration) error {
+	tp := sdktrace.NewTracerProvider()
+	_, span := tp.Tracer("test").Start(context.Background(), "test")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return exporter.ExportSpans(ctx, []sdktrace.ReadOnlySpan{span.(sdktrace.ReadOnlySpan)})
+}
+
+func TestOTLPExporterMutualTLS(t *testing.T) {
+	certs := newTestCerts(t)
+	endpoint, collector := startTLSCollector(t, certs)
+
+	config := &TraceConfig{Exporter: ExporterConfig{Type: "otlp", OTLP: OTLPConfig{
+		Endpoint: endpoint,
+		Timeout:  5,
+		TLS: TLSConfig{
+			CAFile:     certs.caFile,
+			CertFile:   certs.clientCert,
+			KeyFile:    certs.clientKey,
+			ServerName: "localhost",
+		},
+	}}}
+	if err := validateConfig(&TraceConfig{Enabled: true, Exporter: config.Exporter}); err != nil {
+		t.Fatalf("validateConfig() error = %v", err)
+	}
+
+	exporter, err := createOTLPExporter(config)
+	if err != nil {
+		t.Fatalf("createOTLPExporter() error = %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	if err := exportOneSpan(exporter, 5*time.Second); err != nil {
+		t.Fatalf("export over mTLS error = %v", err)
+	}
+	if collector.count() != 1 {
+		t.Errorf("collector received %d spans, want 1", collector.count())
+	}
+}
+
+func TestOTLPExporterTLSWithoutClientCert(t *testing.T) {
+	certs := newTestCerts(t)
+	endpoint, collector := startTLSCollector(t, certs)
+
+	config := &TraceConfig{Exporter: ExporterConfig{Type: "otlp", OTLP: OTLPConfig{
+		Endpoint: endpoint,
+		Timeout:  5,
+		TLS:      TLSConfig{CAFile: certs.caFile, ServerName: "localhost"},
+	}}}
+	exporter, err := createOTLPExporter(config)
+	if err != nil {
+		t.Fatalf("createOTLPExporter() error = %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	// 未开启重试，握手失败会直接返回错误
+	if err := exportOneSpan(exporter, 500*time.Millisecond); err == nil {
+		t.Error("export without client certificate should be rejected")
+	}
+	if collector.count() != 0 {
+		t.Error("collector should not receive spans without client certificate")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	certs := newTestCerts(t)
+
+	tlsConfig, err := newTLSConfig(TLSConfig{
+		CAFile:     certs.caFile,
+		CertFile:   certs.clientCert,
+		KeyFile:    certs.clientKey,
+		ServerName: "collector",
+		MinVersion: "1.3",
+	})
+	if err != nil {
+		t.Fatalf("newTLSConfig() error = %v", err)
+	}
+	if tlsConfig.RootCAs == nil || len(tlsConfig.Certificates) != 1 {
+		t.Error("CA pool and client certificate should be loaded")
+	}
+	if tlsConfig.ServerName != "collector" || tlsConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("unexpected tls.Config: server_name=%s min_version=%x", tlsConfig.ServerName, tlsConfig.MinVersion)
+	}
+
+	if _, err := newTLSConfig(TLSConfig{CAFile: certs.clientKey}); err == nil {
+		t.Error("non-certificate CA file should fail")
+	}
+	if _, err := newTLSConfig(TLSConfig{CertFile: certs.clientCert, KeyFile: certs.serverKey}); err == nil {
+		t.Error("mismatched key pair should fail")
+	}
+}
+
+func TestValidateTLSConfig(t *testing.T) {
+	certs := newTestCerts(t)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name    string
+		otlp    OTLPConfig
+		wantErr bool
+	}{
+		{"no tls", OTLPConfig{Insecure: true}, false},
+		{"ca only", OTLPConfig{TLS: TLSConfig{CAFile: certs.caFile}}, false},
+		{"mutual tls", OTLPConfig{TLS: TLSConfig{CAFile: certs.caFile, CertFile: certs.clientCert, KeyFile: certs.clientKey}}, false},
+		{"tls with insecure", OTLPConfig{Insecure: true, TLS: TLSConfig{CAFile: certs.caFile}}, true},
+		{"missing ca file", OTLPConfig{TLS: TLSConfig{CAFile: missing}}, true},
+		{"missing key file", OTLPConfig{TLS: TLSConfig{CertFile: certs.clientCert, KeyFile: missing}}, true},
+		{"ca file without certificate", OTLPConfig{TLS: TLSConfig{CAFile: certs.clientKey}}, true},
+		{"mismatched key pair", OTLPConfig{TLS: TLSConfig{CertFile: certs.clientCert, KeyFile: certs.serverKey}}, true},
+		{"cert without key", OTLPConfig{TLS: TLSConfig{CertFile: certs.clientCert}}, true},
+		{"invalid min version", OTLPConfig{TLS: TLSConfig{MinVersion: "1.4"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.otlp.Endpoint = "localhost:4317"
+			config := &TraceConfig{Enabled: true, Exporter: ExporterConfig{Type: "otlp", OTLP: tt.otlp}}
+			err := validateConfig(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package zltrace
 import (
 	"context"
 	"sync"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ============================================================================
@@ -29,20 +31,181 @@ type Tracer interface {
 
 // Span 定义分布式追踪 span 接口
 type Span interface {
-	// Context 返回 span 的 context
+	// Context 返回携带该 span 的 context（基于 context.Background()）
+	// 用于把 span 交给其他 goroutine 继续 trace；需要保留请求的 deadline 和值时使用 AttachSpan
 	Context() context.Context
 
 	// SetTag 设置标签
+	// 数值、布尔、切片等类型会保留原始类型，便于追踪系统聚合和范围过滤
+	// time.Duration 转换为毫秒数（INT64），key 中建议带上单位，例如 db.duration_ms
 	SetTag(key string, value interface{})
 
-	// SetError 设置错误信息
-	SetError(err error)
+	// SetTags 批量设置标签
+	SetTags(attrs ...Attribute)
+
+	// SetError 记录错误
+	// 以 OTel exception 事件记录错误类型和信息，并将 span 状态设置为 Error；
+	// 传入 WithStackTrace() 时同时记录调用栈
+	SetError(err error, opts ...ErrorOption)
+
+	// AddEvent 添加带时间戳的事件（如缓存未命中、重试、关键检查点）
+	AddEvent(name string, attrs ...Attribute)
+
+	// SetStatus 设置 span 状态
+	// description 仅在 StatusError 时有意义
+	SetStatus(code StatusCode, description string)
 
 	// Finish 结束 span
 	Finish()
 
 	// TraceID 返回 trace_id
 	TraceID() string
+
+	// SpanID 返回 span_id（16 位十六进制字符串）
+	SpanID() string
+
+	// IsRecording 是否正在记录（未结束且未被采样器丢弃）
+	IsRecording() bool
+
+	// IsSampled 是否被采样（会被导出到追踪系统）
+	IsSampled() bool
+
+	// SpanContext 返回可移植的 span 上下文，可用于手动传播
+	SpanContext() SpanContext
+}
+
+// StatusCode 定义 span 状态
+type StatusCode int
+
+const (
+	// StatusUnset 未设置（默认），追踪系统按成功处理
+	StatusUnset StatusCode = iota
+	// StatusOK 明确标记为成功
+	StatusOK
+	// StatusError 失败，追踪系统会将该 span 标记为错误
+	StatusError
+)
+
+// String 返回状态名称
+func (c StatusCode) String() string {
+	switch c {
+	case StatusOK:
+		return "ok"
+	case StatusError:
+		return "error"
+	default:
+		return "unset"
+	}
+}
+
+// FinishSpan 按统一规则设置状态后结束 span
+//
+// err != nil 时调用 SetError（记录 exception 事件并将状态设为 Error），
+// 否则保持状态不变。适用于 Kafka 发送/消费等以 error 表示结果的操作。
+//
+// 使用示例：
+//
+//	span, ctx := saramatracer.StartKafkaProducerSpan(ctx, msg)
+//	_, _, err := producer.SendMessage(msg)
+//	zltrace.FinishSpan(span, err)
+func FinishSpan(span Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.SetError(err)
+	}
+	span.Finish()
+}
+
+// ErrorConfig SetError 的配置，由 ErrorOption 填充
+type ErrorConfig struct {
+	// StackTrace 是否记录调用栈（exception.stacktrace）
+	StackTrace bool
+}
+
+// ErrorOption SetError 的可选参数
+type ErrorOption func(*ErrorConfig)
+
+// NewErrorConfig 依次应用 opts，返回最终配置
+func NewErrorConfig(opts ...ErrorOption) ErrorConfig {
+	var config ErrorConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&config)
+		}
+	}
+	return config
+}
+
+// WithStackTrace 记录错误时同时记录调用栈
+//
+// 使用示例：
+//
+//	if err != nil {
+//	    span.SetError(err, zltrace.WithStackTrace())
+//	}
+func WithStackTrace() ErrorOption {
+	return func(c *ErrorConfig) {
+		c.StackTrace = true
+	}
+}
+
+// SpanKind 定义 span 的类型
+// 追踪系统（SkyWalking、Jaeger 等）依赖 span 类型绘制服务拓扑：
+// Server/Consumer 表示入口，Client/Producer 表示出口
+type SpanKind int
+
+const (
+	// SpanKindInternal 进程内部操作（默认）
+	SpanKindInternal SpanKind = iota
+	// SpanKindServer 服务端入口，如 HTTP 服务端处理请求
+	SpanKindServer
+	// SpanKindClient 客户端出口，如调用外部 HTTP 服务
+	SpanKindClient
+	// SpanKindProducer 消息生产者，如发送 Kafka 消息
+	SpanKindProducer
+	// SpanKindConsumer 消息消费者，如处理 Kafka 消息
+	SpanKindConsumer
+)
+
+// String 返回 span 类型名称
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	case SpanKindProducer:
+		return "producer"
+	case SpanKindConsumer:
+		return "consumer"
+	default:
+		return "internal"
+	}
+}
+
+// SpanKindTracer 支持指定 span 类型的 Tracer（可选接口）
+// OTELTracer 实现了此接口；自定义 Tracer 可以按需实现
+type SpanKindTracer interface {
+	// StartSpanWithKind 启动一个指定类型的 span
+	StartSpanWithKind(ctx context.Context, operationName string, kind SpanKind) (Span, context.Context)
+}
+
+// StartSpanWithKind 使用指定的 span 类型启动 span
+//
+// 如果 tracer 实现了 SpanKindTracer，由 tracer 原生设置类型；
+// 否则等价于 StartSpanWithOptions(tracer, ctx, operationName, WithSpanKind(kind))。
+//
+// 使用示例：
+//
+//	span, ctx := zltrace.StartSpanWithKind(zltrace.GetSafeTracer(), ctx, "GET /users", zltrace.SpanKindServer)
+//	defer span.Finish()
+func StartSpanWithKind(tracer Tracer, ctx context.Context, operationName string, kind SpanKind) (Span, context.Context) {
+	if kt, ok := tracer.(SpanKindTracer); ok {
+		return kt.StartSpanWithKind(ctx, operationName, kind)
+	}
+	return StartSpanWithOptions(tracer, ctx, operationName, WithSpanKind(kind))
 }
 
 // Carrier 定义 trace 上下文载体接口
@@ -126,18 +289,64 @@ func (p *tracerProvider) Name() string {
 type contextKey int
 
 const (
-_spanKey contextKey = iota
+	_spanKey contextKey = iota
 )
 
 // SpanFromContext 从 context 中获取 span
+//
+// 按以下顺序查找：
+//  1. 通过 ContextWithSpan 存入的自定义 Span（非 OpenTelemetry 实现的 Tracer）
+//  2. OpenTelemetry Span（由 OTELTracer、OTel SDK 或第三方 OTel 插桩存入）
+//
+// 无论 span 由谁创建，都返回 zltrace 的 Span 包装；context 中没有有效 span 时返回 nil。
 func SpanFromContext(ctx context.Context) Span {
+	if ctx == nil {
+		return nil
+	}
 	if span, ok := ctx.Value(_spanKey).(Span); ok {
 		return span
 	}
+	if otelSpan := trace.SpanFromContext(ctx); otelSpan.SpanContext().IsValid() {
+		return &OTELSpan{span: otelSpan}
+	}
 	return nil
 }
 
 // ContextWithSpan 将 span 添加到 context
+//
+// OTELSpan 直接存入 OpenTelemetry 的 context（与 OTel SDK 共用同一份上下文），
+// 这样第三方 OTel 插桩也能看到它；其他 Span 实现存入 zltrace 自己的 key。
 func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	if otelSpan, ok := span.(*OTELSpan); ok {
+		// 屏蔽外层可能存在的自定义 span，确保 SpanFromContext 返回最新的 span
+		if ctx.Value(_spanKey) != nil {
+			ctx = context.WithValue(ctx, _spanKey, nil)
+		}
+		return trace.ContextWithSpan(ctx, otelSpan.span)
+	}
 	return context.WithValue(ctx, _spanKey, span)
 }
+
+// AttachSpan 把 span 挂到任意父 context 上，返回的 context 以该 span 作为当前 span
+//
+// 与 ContextWithSpan 不同，parent 或 span 为 nil 时也可以安全调用：
+// parent 为 nil 时使用 context.Background()，span 为 nil 或空操作 span 时原样返回 parent。
+// 返回的 context 保留 parent 的 deadline、取消信号和其他值。
+//
+// 使用示例：
+//
+//	// 在另一个请求的 context 中继续 span 所在的 trace
+//	ctx = zltrace.AttachSpan(r.Context(), span)
+//	child, ctx := zltrace.GetSafeTracer().StartSpan(ctx, "worker.process")
+func AttachSpan(parent context.Context, span Span) context.Context {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if span == nil {
+		return parent
+	}
+	if _, ok := span.(*noOpSpan); ok {
+		return parent
+	}
+	return ContextWithSpan(parent, span)
+}
@@ -11,7 +11,7 @@ import (
 // Gin 框架适配器
 // ============================================================================
 
-// ginHTTPHandler 实现zltrace.HTTPTraceHandler接口（Gin框架）
+// ginHTTPHandler 实现zltrace.HTTPTraceHandler、HTTPStatusHandler和HTTPRouteHandler接口（Gin框架）
 type ginHTTPHandler struct {
 	c *gin.Context
 }
@@ -36,6 +36,15 @@ func (h *ginHTTPHandler) GetSpanContext() context.Context {
 	return h.c.Request.Context()
 }
 
+func (h *ginHTTPHandler) GetStatusCode() int {
+	return h.c.Writer.Status()
+}
+
+// GetRoute 返回匹配的路由模板（gin 在执行中间件之前已完成路由匹配）
+func (h *ginHTTPHandler) GetRoute() string {
+	return h.c.FullPath()
+}
+
 // TraceMiddleware 自动创建HTTP请求span的Gin中间件
 // 使用示例：
 //
@@ -27,29 +27,55 @@ import (
 // 返回：
 //   - context.Context: 包含 trace_id 的 context
 func CreateKafkaConsumerContext(message *kafka.Message) context.Context {
-	tracer := zltrace.GetTracer()
-	if tracer == nil {
+	if zltrace.GetTracer() == nil {
 		// 没有注册 tracer，返回 background context
 		return context.Background()
 	}
 
+	span, ctx := StartKafkaConsumerSpan(message)
+	span.Finish()
+
+	return ctx
+}
+
+// StartKafkaConsumerSpan 从 Kafka 消息中提取 trace 上下文，并创建 Consumer Span
+//
+// 与 CreateKafkaConsumerContext 不同，返回的 span 不会立即结束，
+// 可以覆盖整个消息处理过程。处理完成后调用 zltrace.FinishSpan(span, err)：
+// 处理失败（err != nil）时 span 会记录错误并标记为 Error。
+// 没有注册 tracer 时返回空操作 span。
+//
+// **使用示例**：
+//
+//	span, ctx := StartKafkaConsumerSpan(msg)
+//	err := handleMessage(ctx, msg)
+//	zltrace.FinishSpan(span, err)
+func StartKafkaConsumerSpan(message *kafka.Message) (zltrace.Span, context.Context) {
+	tracer := zltrace.GetSafeTracer()
+
 	// 使用 tracer.Extract 从消息 headers 中提取 trace 上下文
 	carrier := &kafkaConsumerHeaderCarrier{headers: message.Headers}
 	ctx, err := tracer.Extract(context.Background(), carrier)
-
-	if err != nil {
+	if err != nil || ctx == nil {
 		// 提取失败（没有 trace 信息），创建新的 trace_id
 		// ✅ 保险逻辑：确保总是有 trace_id
-		span, newCtx := tracer.StartSpan(context.Background(), "Kafka/Consume")
-		span.Finish()
-		return newCtx
+		ctx = context.Background()
 	}
 
-	// 提取成功，基于提取的 context 创建 span
-	span, spanCtx := tracer.StartSpan(ctx, "Kafka/Consume")
-	span.Finish()
+	// 基于提取的 context 创建 span（有上游 trace 则继承）
+	return zltrace.StartSpanWithOptions(tracer, ctx, "Kafka/Consume", consumerSpanOptions(message)...)
+}
 
-	return spanCtx
+// consumerSpanOptions 消费 span 的创建参数（类型和消息基本信息）
+func consumerSpanOptions(message *kafka.Message) []zltrace.StartSpanOption {
+	return []zltrace.StartSpanOption{
+		zltrace.WithSpanKind(zltrace.SpanKindConsumer),
+		zltrace.WithAttributes(
+			zltrace.Attr("kafka.topic", message.Topic),
+			zltrace.Attr("kafka.partition", message.Partition),
+			zltrace.Attr("kafka.offset", message.Offset),
+		),
+	}
 }
 
 // kafkaConsumerHeaderCarrier 实现 Carrier 接口，用于 Kafka Consumer Headers
@@ -109,32 +135,48 @@ func (c *kafkaConsumerHeaderCarrier) Get(key string) (string, bool) {
 // 返回：
 //   - context.Context: 包含 span 的 context（建议用于后续操作）
 func InjectKafkaProducerHeaders(ctx context.Context, msg *kafka.Message) context.Context {
-	tracer := zltrace.GetTracer()
-	if tracer == nil {
+	if zltrace.GetTracer() == nil {
 		// 没有注册 tracer，返回原 context（优雅降级）
 		return ctx
 	}
 
-	// 1. 创建 Exit Span（表示发送消息到 Kafka）
-	// span 的 operationName 格式：Kafka/Produce/{topic}
+	// 创建 Exit Span 并注入 trace_id 到消息 headers
+	// 这里拿不到发送结果，span 立即结束；需要记录发送结果时使用 StartKafkaProducerSpan
+	span, spanCtx := StartKafkaProducerSpan(ctx, msg)
+	span.Finish()
+
+	return spanCtx
+}
+
+// StartKafkaProducerSpan 创建 Producer Span，并将 trace 上下文注入到消息 headers
+//
+// 与 InjectKafkaProducerHeaders 不同，返回的 span 不会立即结束，
+// 可以覆盖实际的发送过程。发送完成后调用 zltrace.FinishSpan(span, err)：
+// 发送失败（err != nil）时 span 会记录错误并标记为 Error。
+// 没有注册 tracer 时返回空操作 span（headers 不会被修改）。
+//
+// span 的 operationName 格式：Kafka/Produce/{topic}
+func StartKafkaProducerSpan(ctx context.Context, msg *kafka.Message) (zltrace.Span, context.Context) {
+	tracer := zltrace.GetSafeTracer()
+
+	// 消息基本信息在创建 span 时设置，采样器可以看到
 	operationName := "Kafka/Produce/" + msg.Topic
-	span, spanCtx := tracer.StartSpan(ctx, operationName)
-	defer span.Finish()
+	attrs := []zltrace.Attribute{zltrace.Attr("kafka.topic", msg.Topic)}
+	if len(msg.Key) > 0 {
+		attrs = append(attrs, zltrace.Attr("kafka.key", string(msg.Key)))
+	}
+	span, spanCtx := zltrace.StartSpanWithOptions(tracer, ctx, operationName,
+		zltrace.WithSpanKind(zltrace.SpanKindProducer),
+		zltrace.WithAttributes(attrs...))
 
-	// 2. 注入 trace_id 到消息 headers
+	// 注入 trace_id 到消息 headers
 	carrier := &kafkaProducerHeaderCarrier{headers: &msg.Headers}
 	if err := tracer.Inject(spanCtx, carrier); err != nil {
 		// 注入失败不应该阻止发送消息，记录日志后继续
 		// 这里不使用 zllog 避免循环依赖
 	}
 
-	// 3. 设置 span 标签
-	span.SetTag("kafka.topic", msg.Topic)
-	if len(msg.Key) > 0 {
-		span.SetTag("kafka.key", string(msg.Key))
-	}
-
-	return spanCtx
+	return span, spanCtx
 }
 
 // kafkaProducerHeaderCarrier 实现 Carrier 接口，用于 Kafka Producer Headers
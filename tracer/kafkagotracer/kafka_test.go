@@ -2,11 +2,13 @@ package kafkagotracer
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"testing"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/zlxdbj/zltrace"
+	"github.com/zlxdbj/zltrace/zltracetest"
 )
 
 func TestCreateKafkaConsumerContext(t *testing.T) {
@@ -92,6 +94,99 @@ func TestInjectKafkaProducerHeaders(t *testing.T) {
 	}
 }
 
+func TestStartKafkaSpans(t *testing.T) {
+	// 没有注册 tracer 时返回空操作 span，不修改 headers
+	msg := &kafka.Message{Topic: "test-topic", Value: []byte("test")}
+	span, ctx := StartKafkaProducerSpan(context.Background(), msg)
+	if span == nil || ctx == nil {
+		t.Fatal("span and context should not be nil without tracer")
+	}
+	zltrace.FinishSpan(span, fmt.Errorf("send failed"))
+	if len(msg.Headers) != 0 {
+		t.Error("headers should not be modified without tracer")
+	}
+
+	// 注册 mock tracer
+	zltrace.RegisterTracer(&mockTracer{})
+	defer zltrace.RegisterTracer(nil)
+
+	span, ctx = StartKafkaProducerSpan(context.Background(), msg)
+	if zltrace.SpanFromContext(ctx) == nil {
+		t.Error("producer span should be stored in context")
+	}
+	zltrace.FinishSpan(span, nil)
+	if len(msg.Headers) != 1 {
+		t.Error("traceparent header should be injected")
+	}
+
+	span, ctx = StartKafkaConsumerSpan(msg)
+	if zltrace.SpanFromContext(ctx) == nil {
+		t.Error("consumer span should be stored in context")
+	}
+	zltrace.FinishSpan(span, nil)
+}
+
+func TestKafkaTraceContinuity(t *testing.T) {
+	rec := zltracetest.New(t)
+
+	parent, ctx := rec.Tracer().StartSpan(context.Background(), "order.create")
+	msg := &kafka.Message{Topic: "orders", Key: []byte("order-1"), Partition: 3, Offset: 42}
+	produce, _ := StartKafkaProducerSpan(ctx, msg)
+	zltrace.FinishSpan(produce, nil)
+	parent.Finish()
+
+	consume, _ := StartKafkaConsumerSpan(msg)
+	zltrace.FinishSpan(consume, fmt.Errorf("handle failed"))
+
+	produceSpan := rec.MustSpan(t, "Kafka/Produce/orders")
+	consumeSpan := rec.MustSpan(t, "Kafka/Consume")
+
+	zltracetest.AssertParent(t, rec.MustSpan(t, "order.create"), produceSpan)
+	zltracetest.AssertKind(t, produceSpan, zltrace.SpanKindProducer)
+	zltracetest.AssertAttribute(t, produceSpan, "kafka.topic", "orders")
+	zltracetest.AssertAttribute(t, produceSpan, "kafka.key", "order-1")
+
+	zltracetest.AssertRemoteParent(t, produceSpan, consumeSpan)
+	zltracetest.AssertKind(t, consumeSpan, zltrace.SpanKindConsumer)
+	zltracetest.AssertStatus(t, consumeSpan, zltrace.StatusError)
+	zltracetest.AssertAttribute(t, consumeSpan, "kafka.partition", 3)
+	zltracetest.AssertAttribute(t, consumeSpan, "kafka.offset", 42)
+}
+
+func TestKafkaConsumerWithoutTraceParentStartsNewTrace(t *testing.T) {
+	rec := zltracetest.New(t)
+
+	span, _ := StartKafkaConsumerSpan(&kafka.Message{Topic: "orders"})
+	span.Finish()
+
+	zltracetest.AssertRoot(t, rec.MustSpan(t, "Kafka/Consume"))
+}
+
+var update = flag.Bool("update", false, "update golden trace files in testdata/")
+
+// 更新 golden 文件：go test -run TestKafkaSpansGolden -update
+func TestKafkaSpansGolden(t *testing.T) {
+	rec := zltracetest.New(t)
+
+	// 上游请求内发送消息，消费者继续同一个 trace
+	parent, ctx := rec.Tracer().StartSpan(context.Background(), "order.create")
+	orders := &kafka.Message{Topic: "orders", Key: []byte("order-1"), Partition: 3, Offset: 42}
+	InjectKafkaProducerHeaders(ctx, orders)
+	payments := &kafka.Message{Topic: "payments"}
+	produce, _ := StartKafkaProducerSpan(ctx, payments)
+	zltrace.FinishSpan(produce, fmt.Errorf("broker unavailable"))
+	parent.Finish()
+
+	consume, _ := StartKafkaConsumerSpan(orders)
+	consume.Finish()
+
+	// 没有 trace header 的消息开始新的 trace
+	orphan, _ := StartKafkaConsumerSpan(&kafka.Message{Topic: "audit", Partition: 0, Offset: 7})
+	orphan.Finish()
+
+	rec.AssertTree(t, "kafka_spans", zltracetest.Update(*update))
+}
+
 func TestKafkaProducerHeaderCarrier(t *testing.T) {
 	headers := []kafka.Header{}
 	carrier := &kafkaProducerHeaderCarrier{headers: &headers}
@@ -195,10 +290,32 @@ func (m *mockSpan) Context() context.Context {
 
 func (m *mockSpan) SetTag(key string, value interface{}) {}
 
-func (m *mockSpan) SetError(err error) {}
+func (m *mockSpan) SetTags(attrs ...zltrace.Attribute) {}
+
+func (m *mockSpan) SetError(err error, opts ...zltrace.ErrorOption) {}
+
+func (m *mockSpan) AddEvent(name string, attrs ...zltrace.Attribute) {}
+
+func (m *mockSpan) SetStatus(code zltrace.StatusCode, description string) {}
 
 func (m *mockSpan) Finish() {}
 
 func (m *mockSpan) TraceID() string {
 	return "test123"
 }
+
+func (m *mockSpan) SpanID() string {
+	return "test456"
+}
+
+func (m *mockSpan) IsRecording() bool {
+	return true
+}
+
+func (m *mockSpan) IsSampled() bool {
+	return true
+}
+
+func (m *mockSpan) SpanContext() zltrace.SpanContext {
+	return zltrace.SpanContext{TraceID: "test123", SpanID: "test456", Sampled: true}
+}
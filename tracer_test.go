@@ -48,11 +48,74 @@ func TestContextWithSpan(t *testing.T) {
 	}
 }
 
+func TestNoOpSpanContextRoundTrip(t *testing.T) {
+	span, _ := (&noOpTracer{}).StartSpan(context.Background(), "test")
+	if span.Context() == nil {
+		t.Fatal("noop span Context() should not be nil")
+	}
+
+	type key struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "v"))
+	ctx := AttachSpan(parent, span)
+	if ctx != parent {
+		t.Error("attaching a noop span should return the parent unchanged")
+	}
+	cancel()
+	if ctx.Err() == nil || ctx.Value(key{}) != "v" {
+		t.Error("attached context should keep parent cancellation and values")
+	}
+
+	if AttachSpan(nil, nil) == nil {
+		t.Error("AttachSpan(nil, nil) should return a non-nil context")
+	}
+}
+
+func TestStartSpanWithKindFallback(t *testing.T) {
+	// mockTracer 没有实现 SpanKindTracer，span 类型记录为标签
+	span, ctx := StartSpanWithKind(&mockTracer{}, context.Background(), "test", SpanKindProducer)
+	if span == nil || ctx == nil {
+		t.Fatal("span and context should not be nil")
+	}
+	if got := span.(*mockSpan).tags["span.kind"]; got != "producer" {
+		t.Errorf("span.kind tag = %v, want producer", got)
+	}
+
+	// noOpTracer 实现了 SpanKindTracer
+	span, _ = StartSpanWithKind(GetSafeTracer(), context.Background(), "test", SpanKindServer)
+	span.Finish()
+}
+
+func TestStartSpanWithOptionsFallback(t *testing.T) {
+	// mockTracer 没有实现 OptionsTracer，属性通过 SetTag 补充
+	span, _ := StartSpanWithOptions(&mockTracer{}, context.Background(), "test",
+		WithSpanKind(SpanKindClient),
+		WithAttributes(Attr("http.method", "GET")),
+		WithNewRoot())
+
+	tags := span.(*mockSpan).tags
+	if tags["span.kind"] != "client" {
+		t.Errorf("span.kind tag = %v, want client", tags["span.kind"])
+	}
+	if tags["http.method"] != "GET" {
+		t.Errorf("http.method tag = %v, want GET", tags["http.method"])
+	}
+
+	// WithNewRoot 会屏蔽 context 中已有的 span
+	parentCtx := ContextWithSpan(context.Background(), &mockSpan{})
+	_, ctx := StartSpanWithOptions(&mockTracer{}, parentCtx, "test", WithNewRoot())
+	if SpanFromContext(ctx) == nil {
+		t.Error("new span should be stored in context")
+	}
+	if SpanFromContext(contextWithoutSpan(parentCtx)) != nil {
+		t.Error("contextWithoutSpan should hide the parent span")
+	}
+}
+
 // mockTracer 用于测试
 type mockTracer struct{}
 
 func (m *mockTracer) StartSpan(ctx context.Context, operationName string) (Span, context.Context) {
-	span := &mockSpan{}
+	span := &mockSpan{tags: map[string]interface{}{}}
 	return span, ContextWithSpan(ctx, span)
 }
 
@@ -70,18 +133,50 @@ func (m *mockTracer) Close() error {
 }
 
 // mockSpan 用于测试
-type mockSpan struct{}
+type mockSpan struct {
+	tags map[string]interface{}
+}
 
 func (m *mockSpan) Context() context.Context {
 	return context.Background()
 }
 
-func (m *mockSpan) SetTag(key string, value interface{}) {}
+func (m *mockSpan) SetTag(key string, value interface{}) {
+	if m.tags != nil {
+		m.tags[key] = value
+	}
+}
+
+func (m *mockSpan) SetTags(attrs ...Attribute) {
+	for _, attr := range attrs {
+		m.SetTag(attr.Key, attr.Value)
+	}
+}
+
+func (m *mockSpan) SetError(err error, opts ...ErrorOption) {}
 
-func (m *mockSpan) SetError(err error) {}
+func (m *mockSpan) AddEvent(name string, attrs ...Attribute) {}
+
+func (m *mockSpan) SetStatus(code StatusCode, description string) {}
 
 func (m *mockSpan) Finish() {}
 
 func (m *mockSpan) TraceID() string {
 	return "test123"
 }
+
+func (m *mockSpan) SpanID() string {
+	return "test456"
+}
+
+func (m *mockSpan) IsRecording() bool {
+	return true
+}
+
+func (m *mockSpan) IsSampled() bool {
+	return true
+}
+
+func (m *mockSpan) SpanContext() SpanContext {
+	return SpanContext{TraceID: "test123", SpanID: "test456", Sampled: true}
+}
@@ -0,0 +1,206 @@
+package zltracetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ============================================================================
+// Tree - 将 span 渲染为规范化的调用树
+// ============================================================================
+
+// TreeOption Tree 的可选参数
+type TreeOption func(*treeOptions)
+
+type treeOptions struct {
+	ignore map[string]bool
+	mask   map[string]bool
+	update bool
+}
+
+func newTreeOptions(opts []TreeOption) *treeOptions {
+	o := &treeOptions{ignore: map[string]bool{}, mask: map[string]bool{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// IgnoreAttributes 渲染时不输出这些属性
+func IgnoreAttributes(keys ...string) TreeOption {
+	return func(o *treeOptions) {
+		for _, key := range keys {
+			o.ignore[key] = true
+		}
+	}
+}
+
+// Update 为 true 时 AssertTree/AssertGolden 用实际结果覆盖 golden 文件
+//
+// zltracetest 不注册全局 flag（避免与其他包的同名 flag 冲突），由使用它的测试包定义 -update：
+//
+//	var update = flag.Bool("update", false, "update golden trace files in testdata/")
+//
+//	rec.AssertTree(t, "kafka_spans", zltracetest.Update(*update))
+func Update(update bool) TreeOption {
+	return func(o *treeOptions) {
+		o.update = update
+	}
+}
+
+// MaskAttributes 只记录这些属性存在，值输出为 <masked>
+//
+// 用于每次运行都会变化的值，例如 httptest.Server 的随机端口。
+func MaskAttributes(keys ...string) TreeOption {
+	return func(o *treeOptions) {
+		for _, key := range keys {
+			o.mask[key] = true
+		}
+	}
+}
+
+// Tree 将 span 渲染为规范化的调用树，去掉 trace_id、span_id 和时间，只保留结构
+//
+// 每个 span 一行：名称、[类型]、状态；下面依次是按 key 排序的属性（· 开头）、
+// 事件名称（» 开头）和子 span。父 span 是通过 Inject/Extract 传递过来的，
+// 名称后标记 <remote>。兄弟 span 按开始时间排序，多个 trace 之间用空行分隔。
+//
+// 输出示例：
+//
+//	order.create [internal]
+//	  Kafka/Produce/orders [producer]
+//	    · kafka.topic="orders"
+//	    Kafka/Consume [consumer] <remote> status=Error("handle failed")
+//	      · kafka.offset=42
+//	      » exception
+func Tree(spans tracetest.SpanStubs, opts ...TreeOption) string {
+	o := newTreeOptions(opts)
+
+	ordered := make(tracetest.SpanStubs, len(spans))
+	copy(ordered, spans)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].StartTime.Before(ordered[j].StartTime)
+	})
+
+	recorded := make(map[trace.SpanID]bool, len(ordered))
+	for _, span := range ordered {
+		recorded[span.SpanContext.SpanID()] = true
+	}
+	children := make(map[trace.SpanID][]tracetest.SpanStub)
+	var roots []tracetest.SpanStub
+	for _, span := range ordered {
+		// 父 span 不在记录范围内（上游服务或未结束）时作为根节点
+		if parent := span.Parent.SpanID(); span.Parent.IsValid() && recorded[parent] {
+			children[parent] = append(children[parent], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+
+	var b strings.Builder
+	var write func(span tracetest.SpanStub, depth int)
+	write = func(span tracetest.SpanStub, depth int) {
+		indent := strings.Repeat("  ", depth)
+		b.WriteString(indent + spanLine(span) + "\n")
+		for _, kv := range sortedAttributes(span.Attributes) {
+			key := string(kv.Key)
+			if o.ignore[key] {
+				continue
+			}
+			value := "<masked>"
+			if !o.mask[key] {
+				value = formatValue(kv.Value)
+			}
+			fmt.Fprintf(&b, "%s  · %s=%s\n", indent, key, value)
+		}
+		for _, event := range span.Events {
+			fmt.Fprintf(&b, "%s  » %s\n", indent, event.Name)
+		}
+		for _, child := range children[span.SpanContext.SpanID()] {
+			write(child, depth+1)
+		}
+	}
+	for i, root := range roots {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		write(root, 0)
+	}
+	return b.String()
+}
+
+// Tree 将已记录的 span 渲染为调用树
+func (r *Recorder) Tree(opts ...TreeOption) string {
+	return Tree(r.Spans(), opts...)
+}
+
+// AssertTree 断言已记录的 span 与 testdata/<name>.golden 一致
+func (r *Recorder) AssertTree(t testing.TB, name string, opts ...TreeOption) {
+	t.Helper()
+	AssertGolden(t, name, r.Tree(opts...), opts...)
+}
+
+// AssertGolden 断言 got 与 testdata/<name>.golden 的内容一致
+//
+// 传入 Update(true)（通常来自测试包的 -update 参数）时用 got 覆盖 golden 文件（目录不存在时自动创建），
+// 提交前检查 git diff 确认 span 结构的变化符合预期。opts 中只有 Update 对 AssertGolden 生效。
+func AssertGolden(t testing.TB, name, got string, opts ...TreeOption) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+
+	if newTreeOptions(opts).update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create testdata directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run go test -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("span tree does not match %s (run go test -update if the change is intended)\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+func spanLine(span tracetest.SpanStub) string {
+	line := fmt.Sprintf("%s [%s]", span.Name, span.SpanKind)
+	if span.Parent.IsRemote() {
+		line += " <remote>"
+	}
+	switch span.Status.Code {
+	case codes.Ok:
+		line += " status=Ok"
+	case codes.Error:
+		line += fmt.Sprintf(" status=Error(%q)", span.Status.Description)
+	}
+	return line
+}
+
+func sortedAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	sorted := make([]attribute.KeyValue, len(attrs))
+	copy(sorted, attrs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+	return sorted
+}
+
+// formatValue 字符串加引号，便于区分空字符串和数字
+func formatValue(v attribute.Value) string {
+	if v.Type() == attribute.STRING {
+		return fmt.Sprintf("%q", v.AsString())
+	}
+	return v.Emit()
+}
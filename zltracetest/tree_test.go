@@ -0,0 +1,71 @@
+package zltracetest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zlxdbj/zltrace"
+)
+
+func TestTree(t *testing.T) {
+	rec := New(t)
+	tracer := rec.Tracer()
+
+	root, ctx := zltrace.StartSpanWithKind(tracer, context.Background(), "checkout", zltrace.SpanKindServer)
+	first, _ := zltrace.StartSpanWithOptions(tracer, ctx, "db.query",
+		zltrace.WithSpanKind(zltrace.SpanKindClient),
+		zltrace.WithAttributes(zltrace.Attr("db.table", "orders"), zltrace.Attr("addr", "10.0.0.1:3306"), zltrace.Attr("attempt", 1)))
+	zltrace.FinishSpan(first, errors.New("deadlock"))
+
+	// 跨进程：子 span 的父 span 来自 carrier
+	carrier := MapCarrier{}
+	second, sendCtx := tracer.StartSpan(ctx, "send")
+	tracer.Inject(sendCtx, carrier)
+	remoteCtx, _ := tracer.Extract(context.Background(), carrier)
+	receive, _ := tracer.StartSpan(remoteCtx, "receive")
+	receive.SetStatus(zltrace.StatusOK, "")
+	receive.Finish()
+	second.Finish()
+	root.Finish()
+
+	// 另一个 trace
+	other, _ := tracer.StartSpan(context.Background(), "cleanup")
+	other.Finish()
+
+	got := rec.Tree(MaskAttributes("addr"), IgnoreAttributes("attempt"))
+	want := `checkout [server]
+  db.query [client] status=Error("deadlock")
+    · addr=<masked>
+    · db.table="orders"
+    · error="deadlock"
+    » exception
+  send [internal]
+    receive [internal] <remote> status=Ok
+
+cleanup [internal]
+`
+	if got != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// -update 时创建 golden 文件
+	AssertGolden(t, "span_tree", "root [server]\n", Update(true))
+	if data, err := os.ReadFile(filepath.Join("testdata", "span_tree.golden")); err != nil || string(data) != "root [server]\n" {
+		t.Fatalf("golden file = %q, err = %v", data, err)
+	}
+
+	AssertGolden(t, "span_tree", "root [server]\n", Update(false))
+
+	ft := &fakeT{TB: t}
+	AssertGolden(ft, "span_tree", "root [client]\n")
+	if len(ft.failures) != 1 {
+		t.Errorf("mismatch should be reported once, failures = %v", ft.failures)
+	}
+}
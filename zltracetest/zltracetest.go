@@ -0,0 +1,266 @@
+// Package zltracetest 提供单元测试用的 span 记录器和断言函数
+//
+// New 注册一个真实的 OTELTracer（基于内存 Exporter，span 结束后立即可见），
+// 测试结束时自动恢复原来的 Tracer。适配器的测试可以直接断言真实的 span 数据，
+// 不需要各自实现 mockTracer。
+//
+// 使用示例：
+//
+//	func TestProducer(t *testing.T) {
+//	    rec := zltracetest.New(t)
+//
+//	    span, _ := kafkagotracer.StartKafkaProducerSpan(ctx, msg)
+//	    zltrace.FinishSpan(span, nil)
+//
+//	    produce := rec.MustSpan(t, "Kafka/Produce/orders")
+//	    zltracetest.AssertKind(t, produce, zltrace.SpanKindProducer)
+//	    zltracetest.AssertAttribute(t, produce, "kafka.topic", "orders")
+//	}
+package zltracetest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zlxdbj/zltrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// ============================================================================
+// Recorder - 记录测试中产生的 span
+// ============================================================================
+
+// Recorder 记录测试中结束的 span
+type Recorder struct {
+	exporter *tracetest.InMemoryExporter
+	tracer   *zltrace.OTELTracer
+}
+
+// New 注册基于内存 Exporter 的 OTELTracer，并在测试结束时恢复原来的 Tracer
+//
+// span 同步导出，Finish 之后立即可以通过 Spans() 查到。
+// 全局 Tracer 是进程级的，使用 New 的测试不要调用 t.Parallel()。
+func New(t testing.TB) *Recorder {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := zltrace.NewOTELTracer(tp, "zltracetest")
+
+	previous := zltrace.GetTracer()
+	zltrace.RegisterTracer(tracer)
+	t.Cleanup(func() {
+		zltrace.RegisterTracer(previous)
+		tracer.Close()
+	})
+
+	return &Recorder{exporter: exporter, tracer: tracer}
+}
+
+// Tracer 返回已注册的 OTELTracer
+func (r *Recorder) Tracer() *zltrace.OTELTracer {
+	return r.tracer
+}
+
+// Spans 返回所有已结束的 span（按结束顺序）
+func (r *Recorder) Spans() tracetest.SpanStubs {
+	return r.exporter.GetSpans()
+}
+
+// Reset 清空已记录的 span
+func (r *Recorder) Reset() {
+	r.exporter.Reset()
+}
+
+// Span 返回第一个名称为 name 的 span
+func (r *Recorder) Span(name string) (tracetest.SpanStub, bool) {
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return tracetest.SpanStub{}, false
+}
+
+// SpansByName 返回所有名称为 name 的 span
+func (r *Recorder) SpansByName(name string) tracetest.SpanStubs {
+	var spans tracetest.SpanStubs
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// MustSpan 返回第一个名称为 name 的 span，不存在时终止测试
+func (r *Recorder) MustSpan(t testing.TB, name string) tracetest.SpanStub {
+	t.Helper()
+	span, ok := r.Span(name)
+	if !ok {
+		t.Fatalf("span %q not found, recorded spans: %v", name, spanNames(r.Spans()))
+	}
+	return span
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+// MapCarrier 基于 map 的 Carrier，用于测试 Inject/Extract
+type MapCarrier map[string]string
+
+// Get 获取值（实现 zltrace.Carrier 接口）
+func (c MapCarrier) Get(key string) (string, bool) {
+	value, ok := c[key]
+	return value, ok
+}
+
+// Set 设置值（实现 zltrace.Carrier 接口）
+func (c MapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// ============================================================================
+// 断言函数
+// ============================================================================
+
+// AssertParent 断言 child 是 parent 的直接子 span（同一个 trace）
+func AssertParent(t testing.TB, parent, child tracetest.SpanStub) {
+	t.Helper()
+	if child.Parent.TraceID() != parent.SpanContext.TraceID() || child.Parent.SpanID() != parent.SpanContext.SpanID() {
+		t.Errorf("span %q: parent = %s/%s, want %q (%s/%s)", child.Name,
+			child.Parent.TraceID(), child.Parent.SpanID(),
+			parent.Name, parent.SpanContext.TraceID(), parent.SpanContext.SpanID())
+	}
+}
+
+// AssertRemoteParent 断言 child 的父 span 是通过 Inject/Extract 传递过来的 parent
+//
+// 用于验证跨进程传播（HTTP header、Kafka header）后 trace 没有断开。
+func AssertRemoteParent(t testing.TB, parent, child tracetest.SpanStub) {
+	t.Helper()
+	AssertParent(t, parent, child)
+	if !child.Parent.IsRemote() {
+		t.Errorf("span %q: parent should be remote (extracted from a carrier)", child.Name)
+	}
+}
+
+// AssertRoot 断言 span 是 trace 的根 span（没有父 span）
+func AssertRoot(t testing.TB, span tracetest.SpanStub) {
+	t.Helper()
+	if span.Parent.IsValid() {
+		t.Errorf("span %q should be a root span, parent = %s", span.Name, span.Parent.SpanID())
+	}
+}
+
+// AssertSameTrace 断言所有 span 属于同一个 trace
+func AssertSameTrace(t testing.TB, spans ...tracetest.SpanStub) {
+	t.Helper()
+	if len(spans) < 2 {
+		return
+	}
+	for _, span := range spans[1:] {
+		if span.SpanContext.TraceID() != spans[0].SpanContext.TraceID() {
+			t.Errorf("span %q: trace_id = %s, want %s (same as %q)", span.Name,
+				span.SpanContext.TraceID(), spans[0].SpanContext.TraceID(), spans[0].Name)
+		}
+	}
+}
+
+// AssertKind 断言 span 的类型
+func AssertKind(t testing.TB, span tracetest.SpanStub, kind zltrace.SpanKind) {
+	t.Helper()
+	if span.SpanKind.String() != kind.String() {
+		t.Errorf("span %q: kind = %s, want %s", span.Name, span.SpanKind, kind)
+	}
+}
+
+// AssertStatus 断言 span 的状态
+func AssertStatus(t testing.TB, span tracetest.SpanStub, code zltrace.StatusCode) {
+	t.Helper()
+	if got := statusCode(span.Status.Code); got != code {
+		t.Errorf("span %q: status = %s (%q), want %s", span.Name, got, span.Status.Description, code)
+	}
+}
+
+// AssertAttribute 断言 span 的属性值
+//
+// 整数统一按 int64、浮点数按 float64 比较，因此可以直接写 AssertAttribute(t, span, "kafka.partition", 3)。
+func AssertAttribute(t testing.TB, span tracetest.SpanStub, key string, want interface{}) {
+	t.Helper()
+	value, ok := Attribute(span, key)
+	if !ok {
+		t.Errorf("span %q: attribute %q not found", span.Name, key)
+		return
+	}
+	if got := value.AsInterface(); !reflect.DeepEqual(got, normalize(want)) {
+		t.Errorf("span %q: attribute %q = %v (%T), want %v (%T)", span.Name, key, got, got, want, want)
+	}
+}
+
+// AssertNoAttribute 断言 span 没有某个属性
+func AssertNoAttribute(t testing.TB, span tracetest.SpanStub, key string) {
+	t.Helper()
+	if value, ok := Attribute(span, key); ok {
+		t.Errorf("span %q: attribute %q should not be set, got %v", span.Name, key, value.Emit())
+	}
+}
+
+// Attribute 返回 span 的属性值
+func Attribute(span tracetest.SpanStub, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// statusCode 将 OTel 的状态码转换为 zltrace 的 StatusCode
+func statusCode(code codes.Code) zltrace.StatusCode {
+	switch code {
+	case codes.Ok:
+		return zltrace.StatusOK
+	case codes.Error:
+		return zltrace.StatusError
+	default:
+		return zltrace.StatusUnset
+	}
+}
+
+// normalize 将期望值转换为 OTel 属性中保存的类型
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint8:
+		return int64(x)
+	case uint16:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case float32:
+		return float64(x)
+	case []int:
+		s := make([]int64, len(x))
+		for i := range x {
+			s[i] = int64(x[i])
+		}
+		return s
+	}
+	return v
+}
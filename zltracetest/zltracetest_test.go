@@ -0,0 +1,122 @@
+package zltracetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zlxdbj/zltrace"
+)
+
+// fakeT 记录断言失败，用于验证断言函数本身
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	previous := zltrace.GetTracer()
+
+	t.Run("records spans", func(t *testing.T) {
+		rec := New(t)
+		if zltrace.GetTracer() != rec.Tracer() {
+			t.Fatal("New should register the recorder's tracer")
+		}
+
+		parent, ctx := zltrace.StartSpanWithKind(zltrace.GetSafeTracer(), context.Background(), "parent", zltrace.SpanKindServer)
+		child, _ := zltrace.StartSpanWithOptions(zltrace.GetSafeTracer(), ctx, "child",
+			zltrace.WithSpanKind(zltrace.SpanKindClient),
+			zltrace.WithAttributes(zltrace.Attr("retries", 3), zltrace.Attr("peer", "db")))
+		zltrace.FinishSpan(child, errors.New("timeout"))
+		parent.Finish()
+
+		if got := len(rec.Spans()); got != 2 {
+			t.Fatalf("recorded %d spans, want 2", got)
+		}
+		p, c := rec.MustSpan(t, "parent"), rec.MustSpan(t, "child")
+		AssertRoot(t, p)
+		AssertParent(t, p, c)
+		AssertSameTrace(t, p, c)
+		AssertKind(t, p, zltrace.SpanKindServer)
+		AssertKind(t, c, zltrace.SpanKindClient)
+		AssertStatus(t, c, zltrace.StatusError)
+		AssertAttribute(t, c, "retries", 3)
+		AssertAttribute(t, c, "peer", "db")
+		AssertNoAttribute(t, p, "peer")
+
+		if len(rec.SpansByName("child")) != 1 {
+			t.Error("SpansByName should find the child span")
+		}
+		rec.Reset()
+		if _, ok := rec.Span("parent"); ok || len(rec.Spans()) != 0 {
+			t.Error("Reset should clear recorded spans")
+		}
+	})
+
+	if zltrace.GetTracer() != previous {
+		t.Error("the previous tracer should be restored after the test")
+	}
+}
+
+func TestRemoteParent(t *testing.T) {
+	rec := New(t)
+	tracer := rec.Tracer()
+
+	producer, ctx := tracer.StartSpan(context.Background(), "produce")
+	headers := MapCarrier{}
+	tracer.Inject(ctx, headers)
+	producer.Finish()
+
+	consumerCtx, err := tracer.Extract(context.Background(), headers)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	consumer, _ := tracer.StartSpan(consumerCtx, "consume")
+	consumer.Finish()
+
+	AssertRemoteParent(t, rec.MustSpan(t, "produce"), rec.MustSpan(t, "consume"))
+}
+
+func TestAssertionsReportFailures(t *testing.T) {
+	rec := New(t)
+	a, _ := rec.Tracer().StartSpan(context.Background(), "a")
+	a.SetTag("count", 1)
+	a.Finish()
+	b, ctx := rec.Tracer().StartSpan(context.Background(), "b")
+	c, _ := rec.Tracer().StartSpan(ctx, "c")
+	c.Finish()
+	b.Finish()
+	spanA, spanB, spanC := rec.MustSpan(t, "a"), rec.MustSpan(t, "b"), rec.MustSpan(t, "c")
+
+	tests := []struct {
+		name   string
+		assert func(t testing.TB)
+	}{
+		{"parent", func(t testing.TB) { AssertParent(t, spanA, spanC) }},
+		{"remote parent", func(t testing.TB) { AssertRemoteParent(t, spanB, spanC) }},
+		{"root", func(t testing.TB) { AssertRoot(t, spanC) }},
+		{"same trace", func(t testing.TB) { AssertSameTrace(t, spanA, spanB) }},
+		{"kind", func(t testing.TB) { AssertKind(t, spanA, zltrace.SpanKindServer) }},
+		{"status", func(t testing.TB) { AssertStatus(t, spanA, zltrace.StatusError) }},
+		{"attribute value", func(t testing.TB) { AssertAttribute(t, spanA, "count", 2) }},
+		{"attribute type", func(t testing.TB) { AssertAttribute(t, spanA, "count", "1") }},
+		{"missing attribute", func(t testing.TB) { AssertAttribute(t, spanA, "missing", 1) }},
+		{"unexpected attribute", func(t testing.TB) { AssertNoAttribute(t, spanA, "count") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{TB: t}
+			tt.assert(ft)
+			if len(ft.failures) != 1 {
+				t.Errorf("want exactly one failure, got %v", ft.failures)
+			}
+		})
+	}
+}